@@ -0,0 +1,229 @@
+package cargo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Bin is an executable target of a Cargo package
+type Bin struct {
+	Name    string
+	Package string
+}
+
+// Project describes the binaries of a Cargo package or workspace. Package
+// is the name of the root package, empty for a virtual workspace.
+type Project struct {
+	Dir        string
+	Package    string
+	DefaultRun string
+	Bins       []Bin
+}
+
+// Load reads the Cargo.toml in dir and, for workspaces, the manifests of
+// all members. Binaries come from [[bin]] entries, src/main.rs and src/bin.
+func Load(dir string) (*Project, error) {
+	tables, err := parseFile(filepath.Join(dir, "Cargo.toml"))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Project{
+		Dir:        dir,
+		Package:    get(tables, "package", "name"),
+		DefaultRun: get(tables, "package", "default-run"),
+	}
+	if has(tables, "package") {
+		p.Bins = append(p.Bins, packageBins(dir, tables)...)
+	}
+
+	for _, pattern := range getArray(tables, "workspace", "members") {
+		matches, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+		sort.Strings(matches)
+		for _, member := range matches {
+			if filepath.Clean(member) == filepath.Clean(dir) {
+				continue
+			}
+			mt, err := parseFile(filepath.Join(member, "Cargo.toml"))
+			if err != nil {
+				continue
+			}
+			p.Bins = append(p.Bins, packageBins(member, mt)...)
+		}
+	}
+	return p, nil
+}
+
+// packageBins returns the binaries of the package whose manifest is tables
+func packageBins(dir string, tables []table) []Bin {
+	name := get(tables, "package", "name")
+
+	var bins []Bin
+	seen := make(map[string]bool)
+	paths := make(map[string]bool)
+	add := func(bin, file string) {
+		if bin == "" || seen[bin] || paths[file] {
+			return
+		}
+		seen[bin] = true
+		paths[file] = true
+		bins = append(bins, Bin{Name: bin, Package: name})
+	}
+
+	for _, t := range tables {
+		if t.name != "bin" {
+			continue
+		}
+		bin, _ := t.values["name"].(string)
+		file, _ := t.values["path"].(string)
+		if file == "" {
+			file = "src/bin/" + bin + ".rs"
+			if bin == name {
+				file = "src/main.rs"
+			}
+		}
+		add(bin, filepath.Clean(filepath.FromSlash(file)))
+	}
+
+	// Automatic target discovery, skipping files claimed by a [[bin]] entry
+	if get(tables, "package", "autobins") != "false" {
+		main := filepath.Join("src", "main.rs")
+		if isFile(filepath.Join(dir, main)) {
+			add(name, main)
+		}
+		entries, _ := os.ReadDir(filepath.Join(dir, "src", "bin"))
+		for _, e := range entries {
+			if e.IsDir() {
+				file := filepath.Join("src", "bin", e.Name(), "main.rs")
+				if isFile(filepath.Join(dir, file)) {
+					add(e.Name(), file)
+				}
+			} else if strings.HasSuffix(e.Name(), ".rs") {
+				add(strings.TrimSuffix(e.Name(), ".rs"), filepath.Join("src", "bin", e.Name()))
+			}
+		}
+	}
+	return bins
+}
+
+// Select picks the binary to run among those of package pkg, or of the whole
+// project when pkg is empty: the one named name when given, otherwise the
+// root package default-run, the only binary or the binary named after its
+// package. Like cargo run, a workspace with a root package prefers the
+// binary named after the root package.
+func (p *Project) Select(name, pkg string) (Bin, error) {
+	if name == "" && (pkg == "" || pkg == p.Package) {
+		name = p.DefaultRun
+	}
+
+	bins := p.Bins
+	if pkg != "" {
+		bins = nil
+		for _, b := range p.Bins {
+			if b.Package == pkg {
+				bins = append(bins, b)
+			}
+		}
+		if len(bins) == 0 {
+			return Bin{}, fmt.Errorf("package '%s' has no binary target, available: %s", pkg, names(p.Bins))
+		}
+	}
+
+	if name != "" {
+		for _, b := range bins {
+			if b.Name == name {
+				return b, nil
+			}
+		}
+		return Bin{}, fmt.Errorf("binary '%s' not found, available: %s", name, names(bins))
+	}
+
+	switch len(bins) {
+	case 0:
+		return Bin{}, fmt.Errorf("no binary target found in %s", filepath.Join(p.Dir, "Cargo.toml"))
+	case 1:
+		return bins[0], nil
+	}
+
+	for _, b := range bins {
+		if b.Name == b.Package && (pkg != "" || b.Package == p.Package) {
+			return b, nil
+		}
+	}
+	return Bin{}, fmt.Errorf("multiple binaries found, set run.bin to one of: %s", names(bins))
+}
+
+func names(bins []Bin) string {
+	names := make([]string, 0, len(bins))
+	for _, b := range bins {
+		names = append(names, b.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// TargetDir returns the cargo target directory of the project in dir. It
+// honours CARGO_TARGET_DIR in env, then build.target-dir of the
+// .cargo/config.toml files from dir upwards, and defaults to dir/target.
+func TargetDir(dir string, env []string) string {
+	for i := len(env) - 1; i >= 0; i-- {
+		if strings.HasPrefix(env[i], "CARGO_TARGET_DIR=") {
+			if v := strings.TrimPrefix(env[i], "CARGO_TARGET_DIR="); v != "" {
+				return resolve(dir, v)
+			}
+		}
+	}
+
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		for _, name := range []string{"config.toml", "config"} {
+			tables, err := parseFile(filepath.Join(d, ".cargo", name))
+			if err != nil {
+				continue
+			}
+			if v := get(tables, "build", "target-dir"); v != "" {
+				// Relative to the directory containing .cargo
+				return resolve(d, v)
+			}
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	return filepath.Join(dir, "target")
+}
+
+// ProfileDir returns the directory below the target directory holding the
+// artifacts of a cargo profile
+func ProfileDir(profile string) string {
+	switch profile {
+	case "", "dev", "test":
+		return "debug"
+	case "bench":
+		return "release"
+	}
+	return profile
+}
+
+// Executable returns the path of bin inside the profile directory dir
+func Executable(dir string, bin Bin) string {
+	name := bin.Name
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return filepath.Join(dir, name)
+}
+
+func resolve(dir, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(dir, filepath.FromSlash(p))
+}
+
+func isFile(file string) bool {
+	fi, err := os.Stat(file)
+	return err == nil && !fi.IsDir()
+}
@@ -0,0 +1,145 @@
+package cargo
+
+import (
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "app")
+	writeFile(t, filepath.Join(dir, "Cargo.toml"), `[package]
+name = "app"
+default-run = "app"
+
+[[bin]]
+name = "tool"
+path = "src/bin/helper.rs"
+
+[workspace]
+members = ["crates/*"]
+`)
+	writeFile(t, filepath.Join(dir, "src", "main.rs"), "fn main() {}\n")
+	// Claimed by the [[bin]] entry above, not discovered as "helper"
+	writeFile(t, filepath.Join(dir, "src", "bin", "helper.rs"), "fn main() {}\n")
+	writeFile(t, filepath.Join(dir, "src", "bin", "migrate", "main.rs"), "fn main() {}\n")
+	writeFile(t, filepath.Join(dir, "src", "bin", "notes.txt"), "")
+
+	writeFile(t, filepath.Join(dir, "crates", "api", "Cargo.toml"), "[package]\nname = \"api\"\n")
+	writeFile(t, filepath.Join(dir, "crates", "api", "src", "main.rs"), "fn main() {}\n")
+	writeFile(t, filepath.Join(dir, "crates", "lib", "Cargo.toml"), "[package]\nname = \"lib\"\nautobins = false\n")
+	writeFile(t, filepath.Join(dir, "crates", "lib", "src", "main.rs"), "fn main() {}\n")
+
+	p, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Bin{
+		{Name: "tool", Package: "app"},
+		{Name: "app", Package: "app"},
+		{Name: "migrate", Package: "app"},
+		{Name: "api", Package: "api"},
+	}
+	if !reflect.DeepEqual(p.Bins, want) {
+		t.Errorf("Load() bins = %+v, want %+v", p.Bins, want)
+	}
+	if p.Package != "app" || p.DefaultRun != "app" {
+		t.Errorf("Load() package = %q, default-run = %q, want app", p.Package, p.DefaultRun)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	app := Bin{Name: "app", Package: "app"}
+	tool := Bin{Name: "tool", Package: "app"}
+	api := Bin{Name: "api", Package: "api"}
+	migrate := Bin{Name: "migrate", Package: "api"}
+	worker := Bin{Name: "worker", Package: "worker"}
+
+	tests := []struct {
+		name    string
+		project Project
+		bin     string
+		pkg     string
+		want    Bin
+		wantErr bool
+	}{
+		{name: "explicit", project: Project{Package: "app", Bins: []Bin{app, tool}}, bin: "tool", want: tool},
+		{name: "unknown", project: Project{Package: "app", Bins: []Bin{app}}, bin: "nope", wantErr: true},
+		{name: "default-run", project: Project{Package: "app", DefaultRun: "tool", Bins: []Bin{app, tool}}, want: tool},
+		{name: "only binary", project: Project{Package: "app", Bins: []Bin{tool}}, want: tool},
+		// The directory name does not matter, only the package name
+		{name: "named after package", project: Project{Dir: "/x/app-service", Package: "app", Bins: []Bin{tool, app}}, want: app},
+		{name: "ambiguous", project: Project{Package: "app", Bins: []Bin{tool, {Name: "other", Package: "app"}}}, wantErr: true},
+		{name: "none", project: Project{Dir: "/x/app", Package: "app"}, wantErr: true},
+
+		// Workspaces
+		{name: "root package", project: Project{Package: "app", Bins: []Bin{api, app, worker}}, want: app},
+		{name: "virtual", project: Project{Bins: []Bin{api, worker}}, wantErr: true},
+		{name: "package named binary", project: Project{Bins: []Bin{api, migrate, worker}}, pkg: "api", want: api},
+		{name: "package only binary", project: Project{Bins: []Bin{api, migrate, worker}}, pkg: "worker", want: worker},
+		{name: "package explicit", project: Project{Bins: []Bin{api, migrate, worker}}, bin: "migrate", pkg: "api", want: migrate},
+		{name: "binary of other package", project: Project{Bins: []Bin{api, migrate, worker}}, bin: "worker", pkg: "api", wantErr: true},
+		{name: "unknown package", project: Project{Bins: []Bin{api, worker}}, pkg: "nope", wantErr: true},
+		{name: "default-run of root only", project: Project{Package: "app", DefaultRun: "tool", Bins: []Bin{app, tool, api, migrate}}, pkg: "api", want: api},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.project.Select(tt.bin, tt.pkg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Select() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: Select() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTargetDir(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "ws", "app")
+	writeFile(t, filepath.Join(dir, "Cargo.toml"), "[package]\nname = \"app\"\n")
+
+	if got, want := TargetDir(dir, nil), filepath.Join(dir, "target"); got != want {
+		t.Errorf("TargetDir() default = %q, want %q", got, want)
+	}
+
+	// Relative to the directory holding .cargo, found from dir upwards
+	writeFile(t, filepath.Join(base, "ws", ".cargo", "config.toml"), "[build]\ntarget-dir = \"out/target\"\n")
+	if got, want := TargetDir(dir, nil), filepath.Join(base, "ws", "out", "target"); got != want {
+		t.Errorf("TargetDir() config = %q, want %q", got, want)
+	}
+
+	env := []string{"CARGO_TARGET_DIR=/first", "PATH=/bin", "CARGO_TARGET_DIR=build"}
+	if got, want := TargetDir(dir, env), filepath.Join(dir, "build"); got != want {
+		t.Errorf("TargetDir() env = %q, want %q", got, want)
+	}
+}
+
+func TestProfileDir(t *testing.T) {
+	tests := map[string]string{
+		"":        "debug",
+		"dev":     "debug",
+		"test":    "debug",
+		"release": "release",
+		"bench":   "release",
+		"fast":    "fast",
+	}
+	for profile, want := range tests {
+		if got := ProfileDir(profile); got != want {
+			t.Errorf("ProfileDir(%q) = %q, want %q", profile, got, want)
+		}
+	}
+}
+
+func TestExecutable(t *testing.T) {
+	want := filepath.Join("target", "debug", "app")
+	if runtime.GOOS == "windows" {
+		want += ".exe"
+	}
+	if got := Executable(filepath.Join("target", "debug"), Bin{Name: "app"}); got != want {
+		t.Errorf("Executable() = %q, want %q", got, want)
+	}
+}
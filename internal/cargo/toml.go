@@ -0,0 +1,146 @@
+package cargo
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// table is a TOML table, values are strings or string arrays
+type table struct {
+	name   string
+	values map[string]interface{}
+}
+
+// parseFile reads the small TOML subset used by Cargo manifests and
+// configuration files: [table] and [[array]] headers, string, bare and
+// string array values (arrays may span lines). Inline tables are kept as
+// raw strings.
+func parseFile(file string) ([]table, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tables := []table{{values: make(map[string]interface{})}}
+	current := &tables[0]
+
+	pending := ""
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(stripComment(s.Text()))
+		if pending != "" {
+			line = pending + " " + line
+			pending = ""
+		}
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && !strings.Contains(line, "=") {
+			name := strings.Trim(line, "[] \t")
+			tables = append(tables, table{name: name, values: make(map[string]interface{})})
+			current = &tables[len(tables)-1]
+			continue
+		}
+
+		i := strings.Index(line, "=")
+		if i < 0 {
+			continue
+		}
+		key := unquote(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+
+		if strings.HasPrefix(value, "[") {
+			if strings.Count(value, "[") > strings.Count(value, "]") {
+				pending = line
+				continue
+			}
+			current.values[key] = parseArray(value)
+			continue
+		}
+		current.values[key] = unquote(value)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// get returns the string value of key in the first table named name
+func get(tables []table, name, key string) string {
+	for _, t := range tables {
+		if t.name == name {
+			if v, ok := t.values[key].(string); ok {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// getArray returns the string array value of key in the first table named name
+func getArray(tables []table, name, key string) []string {
+	for _, t := range tables {
+		if t.name == name {
+			if v, ok := t.values[key].([]string); ok {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// has reports whether a table named name exists
+func has(tables []table, name string) bool {
+	for _, t := range tables {
+		if t.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func parseArray(value string) []string {
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(value, "[")
+	value = strings.TrimSuffix(value, "]")
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		items = append(items, unquote(item))
+	}
+	return items
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"' || s[0] == '\'' && s[len(s)-1] == '\'') {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// stripComment removes a trailing # comment outside of strings
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,114 @@
+package cargo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFile(t *testing.T, file, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "Cargo.toml")
+	writeFile(t, file, `# manifest
+top = "level"
+
+[package]
+name = "app" # trailing comment
+"default-run" = 'server'
+edition = 2021
+description = "has # no comment"
+
+[workspace]
+members = [
+    "crates/*", # first
+    'tools/cli',
+]
+exclude = []
+
+[[bin]]
+name = "server"
+path = "src/server.rs"
+
+[[bin]]
+name = "worker"
+
+[dependencies]
+serde = { version = "1", features = ["derive"] }
+`)
+
+	tables, err := parseFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []struct {
+		table, key, want string
+	}{
+		{"", "top", "level"},
+		{"package", "name", "app"},
+		{"package", "default-run", "server"},
+		{"package", "edition", "2021"},
+		{"package", "description", "has # no comment"},
+		{"package", "missing", ""},
+		{"bin", "name", "server"},
+		{"dependencies", "serde", `{ version = "1", features = ["derive"] }`},
+	}
+	for _, tt := range values {
+		if got := get(tables, tt.table, tt.key); got != tt.want {
+			t.Errorf("get(%q, %q) = %q, want %q", tt.table, tt.key, got, tt.want)
+		}
+	}
+
+	if got, want := getArray(tables, "workspace", "members"), []string{"crates/*", "tools/cli"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("getArray(workspace, members) = %q, want %q", got, want)
+	}
+	if got := getArray(tables, "workspace", "exclude"); len(got) != 0 {
+		t.Errorf("getArray(workspace, exclude) = %q, want empty", got)
+	}
+
+	bins := 0
+	for _, tbl := range tables {
+		if tbl.name == "bin" {
+			bins++
+		}
+	}
+	if bins != 2 {
+		t.Errorf("parsed %d [[bin]] tables, want 2", bins)
+	}
+	if !has(tables, "workspace") || has(tables, "lib") {
+		t.Error("has() reports the wrong tables")
+	}
+}
+
+func TestParseFileMissing(t *testing.T) {
+	if _, err := parseFile(filepath.Join(t.TempDir(), "Cargo.toml")); err == nil {
+		t.Error("parseFile() of a missing file error = nil")
+	}
+}
+
+func TestStripComment(t *testing.T) {
+	tests := []struct {
+		line, want string
+	}{
+		{`name = "app"`, `name = "app"`},
+		{`name = "app" # comment`, `name = "app" `},
+		{`name = "a#b"`, `name = "a#b"`},
+		{`name = 'a#b' # c`, `name = 'a#b' `},
+		{`# only a comment`, ``},
+	}
+	for _, tt := range tests {
+		if got := stripComment(tt.line); got != tt.want {
+			t.Errorf("stripComment(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
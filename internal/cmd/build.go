@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/midoks/zzz/internal/logger"
+)
+
+const buildStopTimeout = 5 * time.Second
+
+var (
+	buildTrigger = make(chan bool, 1)
+	buildCancel  context.CancelFunc
+	// buildsCtx is the parent of every build context, it is cancelled when
+	// zzz exits so the build in progress is killed and no new one starts
+	buildsCtx, stopBuildsCtx = context.WithCancel(context.Background())
+	buildLoopDone            = make(chan bool)
+)
+
+// requestBuild cancels the build in progress, if any, and queues a new one.
+// Builds run one at a time, so the queued build always reflects the latest
+// state of the project.
+func requestBuild() {
+	runMutex.Lock()
+	if buildCancel != nil {
+		logger.Log.Info("Newer changes arrived, cancelling build in progress...")
+		buildCancel()
+	}
+	runMutex.Unlock()
+
+	// Non-blocking send to build trigger
+	select {
+	case buildTrigger <- true:
+	default:
+		// Build already queued
+	}
+}
+
+// buildLoop runs the queued builds one after another until stopBuilds
+func buildLoop(rootPath string) {
+	defer close(buildLoopDone)
+
+	for {
+		select {
+		case <-buildsCtx.Done():
+			return
+		case <-buildTrigger:
+			if buildsCtx.Err() != nil {
+				return
+			}
+			CmdDone(rootPath)
+		}
+	}
+}
+
+// stopBuilds cancels the build in progress and waits for the build loop to
+// return, so no build step outlives zzz or writes a build output afterwards
+func stopBuilds() {
+	stopBuildsCtx()
+
+	select {
+	case <-buildLoopDone:
+	case <-time.After(buildStopTimeout):
+		logger.Log.Warnf("Build still running after %s, exiting anyway", buildStopTimeout)
+	}
+}
+
+// newBuildContext returns the context of a new build, cancelled by requestBuild
+// or stopBuilds
+func newBuildContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(buildsCtx)
+
+	runMutex.Lock()
+	buildCancel = cancel
+	runMutex.Unlock()
+
+	return ctx, func() {
+		runMutex.Lock()
+		buildCancel = nil
+		runMutex.Unlock()
+		cancel()
+	}
+}
+
+// runCancelable runs c until it exits or ctx is cancelled. On cancellation the
+// whole process tree is killed, so compiler and linker children go away too.
+func runCancelable(ctx context.Context, c *exec.Cmd) error {
+	c.SysProcAttr = setProcAttributes()
+	if err := c.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan bool)
+	go func() {
+		select {
+		case <-ctx.Done():
+			if err := killProcessGroup(c.Process.Pid); err != nil {
+				c.Process.Kill()
+			}
+		case <-done:
+		}
+	}()
+
+	err := c.Wait()
+	close(done)
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// goBuildArgs returns the "go build" arguments for the build section
+func goBuildArgs(rootPath string) []string {
+	args := []string{"build", "-o", tempOutput(goOutput(rootPath))}
+
+	var tags []string
+	for _, tag := range conf.Build.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > 0 {
+		args = append(args, "-tags", strings.Join(tags, ","))
+	}
+	if conf.Build.Race {
+		args = append(args, "-race")
+	}
+	if conf.Build.Trimpath {
+		args = append(args, "-trimpath")
+	}
+	if gcflags := strings.TrimSpace(conf.Build.Gcflags); gcflags != "" {
+		args = append(args, "-gcflags", gcflags)
+	}
+	if ldflags := strings.TrimSpace(conf.Build.Ldflags); ldflags != "" {
+		args = append(args, "-ldflags", ldflags)
+	}
+	args = append(args, conf.Build.Flags...)
+
+	if conf.Build.Package != "" {
+		args = append(args, conf.Build.Package)
+	}
+	return args
+}
+
+// buildEnv returns the environment of build commands: zzz's own environment,
+// the given defaults and build.env on top. Values may reference other
+// variables as $VAR or ${VAR}.
+func buildEnv(defaults ...string) []string {
+	env := os.Environ()
+	for _, kv := range defaults {
+		// A default never overrides a variable the user already set
+		if i := strings.Index(kv, "="); i > 0 {
+			if _, ok := conf.Build.Env[kv[:i]]; ok {
+				continue
+			}
+			if _, ok := os.LookupEnv(kv[:i]); ok {
+				continue
+			}
+		}
+		env = append(env, kv)
+	}
+
+	keys := make([]string, 0, len(conf.Build.Env))
+	for k := range conf.Build.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+os.ExpandEnv(conf.Build.Env[k]))
+	}
+	return env
+}
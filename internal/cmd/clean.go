@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+
+	"github.com/urfave/cli"
+
+	"github.com/midoks/zzz/internal/logger"
+	"github.com/midoks/zzz/internal/tools"
+)
+
+var Clean = cli.Command{
+	Name:        "clean",
+	Usage:       "remove build artifacts",
+	Description: `remove the binaries built by zzz and leftover hook scripts`,
+	Action:      CmdClean,
+	Flags:       []cli.Flag{},
+}
+
+// hookScriptPattern matches the temporary scripts written by executeScript
+var hookScriptPattern = regexp.MustCompile(`^\.[0-9a-f]{32}\.(sh|bat)$`)
+
+// artifactDir returns the zzz-owned directory the binaries of the project at
+// rootPath are built into, below the user cache directory
+func artifactDir(rootPath string) string {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	name := path.Base(filepath.ToSlash(rootPath)) + "-" + tools.Md5(rootPath)[:8]
+	return filepath.Join(base, "zzz", name)
+}
+
+// tempOutput returns the name a binary is built to before it replaces output
+func tempOutput(output string) string {
+	return filepath.Join(filepath.Dir(output), "."+filepath.Base(output)+".zzz-tmp")
+}
+
+// replaceBinary moves a freshly built binary over output. Windows refuses to
+// replace a running executable, so the app is stopped and the move retried.
+func replaceBinary(tmp, output string) error {
+	err := os.Rename(tmp, output)
+	if err != nil && runtime.GOOS == "windows" {
+		Kill()
+		err = os.Rename(tmp, output)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// removeArtifacts deletes the artifact directory of the project
+func removeArtifacts(rootPath string) {
+	dir := artifactDir(rootPath)
+	if !tools.IsExist(dir) {
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		logger.Log.Warnf("Failed to remove build artifacts %s: %s", dir, err)
+		return
+	}
+	logger.Log.Infof("Removed build artifacts: %s", dir)
+}
+
+func CmdClean(c *cli.Context) error {
+	rootPath, _ := os.Getwd()
+
+	removeArtifacts(rootPath)
+
+	// Go binaries left in the project root by older versions, temp build
+	// outputs and hook scripts of interrupted runs
+	var leftovers []string
+	appName := path.Base(filepath.ToSlash(rootPath))
+	if runtime.GOOS == "windows" {
+		appName += ".exe"
+	}
+	if tools.IsGoP() && isStaleBinary(rootPath, appName) {
+		leftovers = append(leftovers, appName)
+	}
+
+	entries, err := os.ReadDir(rootPath)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if hookScriptPattern.MatchString(e.Name()) || filepath.Ext(e.Name()) == ".zzz-tmp" {
+			leftovers = append(leftovers, e.Name())
+		}
+	}
+
+	for _, name := range leftovers {
+		if err := os.Remove(filepath.Join(rootPath, name)); err != nil {
+			logger.Log.Warnf("Failed to remove %s: %s", name, err)
+			continue
+		}
+		logger.Log.Infof("Removed: %s", name)
+	}
+
+	logger.Log.Success("Clean completed")
+	return nil
+}
+
+// executableMagic are the headers of ELF, Mach-O (32/64 bit, both byte
+// orders, universal) and PE executables
+var executableMagic = [][]byte{
+	[]byte("\x7fELF"),
+	{0xfe, 0xed, 0xfa, 0xce}, {0xfe, 0xed, 0xfa, 0xcf},
+	{0xce, 0xfa, 0xed, 0xfe}, {0xcf, 0xfa, 0xed, 0xfe},
+	{0xca, 0xfe, 0xba, 0xbe},
+	[]byte("MZ"),
+}
+
+// isStaleBinary reports whether name in rootPath looks like a binary an older
+// version of zzz built there: a regular executable file with an executable
+// header that git does not track. Scripts or directories named after the
+// project are never taken for one.
+func isStaleBinary(rootPath, name string) bool {
+	file := filepath.Join(rootPath, name)
+	fi, err := os.Lstat(file)
+	if err != nil || !fi.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0111 == 0 {
+		return false
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	header := make([]byte, 4)
+	n, _ := io.ReadFull(f, header)
+	f.Close()
+
+	binary := false
+	for _, magic := range executableMagic {
+		if n >= len(magic) && bytes.Equal(header[:len(magic)], magic) {
+			binary = true
+			break
+		}
+	}
+	if !binary {
+		return false
+	}
+
+	// A binary committed to the repository is not a build leftover
+	git := exec.Command("git", "ls-files", "--error-unmatch", "--", name)
+	git.Dir = rootPath
+	return git.Run() != nil
+}
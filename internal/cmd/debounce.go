@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"sync"
+	"time"
+
+	"github.com/midoks/zzz/internal/logger"
+)
+
+const (
+	defaultDebounce = 300
+	defaultMaxWait  = 5000
+)
+
+// changeSet coalesces changed files until the debouncer takes them. Adding
+// never blocks and never drops a file, however many changes arrive at once.
+type changeSet struct {
+	mutex  sync.Mutex
+	files  map[string]time.Time
+	notify chan bool
+}
+
+func newChangeSet() *changeSet {
+	return &changeSet{
+		files:  make(map[string]time.Time),
+		notify: make(chan bool, 1),
+	}
+}
+
+// Add records a changed file and wakes up the debouncer
+func (c *changeSet) Add(name string) {
+	c.mutex.Lock()
+	c.files[name] = time.Now()
+	c.mutex.Unlock()
+
+	select {
+	case c.notify <- true:
+	default:
+		// Debouncer already notified
+	}
+}
+
+// Take returns the recorded files and empties the set
+func (c *changeSet) Take() map[string]time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	files := c.files
+	c.files = make(map[string]time.Time)
+	return files
+}
+
+// debounceChanges collects changed files and triggers a build once no change
+// arrived for the debounce period (trailing edge). A steady stream of changes
+// is still built after max_wait, counted from the first change of the burst.
+func debounceChanges(changes *changeSet) {
+	var (
+		pending     bool
+		firstChange time.Time
+	)
+
+	timer := time.NewTimer(time.Hour)
+	stopTimer(timer)
+
+	// Config reload ticker (check every 5 seconds)
+	configTicker := time.NewTicker(5 * time.Second)
+	defer configTicker.Stop()
+
+	for {
+		select {
+		case <-changes.notify:
+			now := time.Now()
+			if !pending {
+				pending = true
+				firstChange = now
+			}
+
+			debounce, maxWait := debounceTimes()
+			wait := debounce
+			if left := maxWait - now.Sub(firstChange); left < wait {
+				wait = left
+			}
+			if wait < 0 {
+				wait = 0
+			}
+
+			stopTimer(timer)
+			timer.Reset(wait)
+
+		case <-timer.C:
+			pending = false
+			fileCount := len(changes.Take())
+			if fileCount == 0 {
+				continue
+			}
+
+			logger.Log.Infof("Detected changes in %d file(s), triggering rebuild...", fileCount)
+			requestBuild()
+
+		case <-configTicker.C:
+			// Check for config file changes
+			reloadConfig()
+		}
+	}
+}
+
+// debounceTimes returns the current quiet period and maximum wait
+func debounceTimes() (time.Duration, time.Duration) {
+	runMutex.RLock()
+	defer runMutex.RUnlock()
+	return time.Duration(conf.Debounce) * time.Millisecond, time.Duration(conf.MaxWait) * time.Millisecond
+}
+
+// stopTimer stops the timer and drains a pending tick so it can be reset
+func stopTimer(timer *time.Timer) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+}
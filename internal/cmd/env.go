@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/midoks/zzz/internal/dotenv"
+	"github.com/midoks/zzz/internal/logger"
+	"github.com/midoks/zzz/internal/tools"
+)
+
+// envFileInterval is how often the run.env_file is checked for changes
+const envFileInterval = time.Second
+
+// passArgs are the arguments given after "--" on the command line
+var passArgs []string
+
+// envFilePath returns the absolute path of run.env_file, or "" when unset
+func envFilePath(rootPath string) string {
+	file := conf.Run.EnvFile
+	if file == "" || filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(rootPath, file)
+}
+
+// runEnv returns the environment of the app: the zzz environment, then the
+// run.env_file variables and the run.env values, which may reference both
+func runEnv(rootPath string) []string {
+	env := os.Environ()
+
+	if file := envFilePath(rootPath); file != "" {
+		values, err := dotenv.Load(file, os.LookupEnv)
+		if err != nil {
+			logger.Log.Warnf("Failed to load env file: %s", err)
+		}
+		env = append(env, values...)
+	}
+
+	keys := make([]string, 0, len(conf.Run.Env))
+	for k := range conf.Run.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	lookup := envLookup(env)
+	for _, k := range keys {
+		env = append(env, k+"="+os.Expand(conf.Run.Env[k], lookup))
+	}
+	return env
+}
+
+// runArgs returns the app arguments, run.args followed by the arguments
+// passed after "--", with $VAR references resolved against env
+func runArgs(env []string) []string {
+	lookup := envLookup(env)
+
+	args := make([]string, 0, len(conf.Run.Args)+len(passArgs))
+	for _, a := range conf.Run.Args {
+		args = append(args, os.Expand(a, lookup))
+	}
+	return append(args, passArgs...)
+}
+
+// envLookup returns a lookup over KEY=VALUE pairs, later pairs win
+func envLookup(env []string) func(string) string {
+	return func(key string) string {
+		for i := len(env) - 1; i >= 0; i-- {
+			if strings.HasPrefix(env[i], key+"=") {
+				return env[i][len(key)+1:]
+			}
+		}
+		return ""
+	}
+}
+
+// watchEnvFile restarts the app without rebuilding when run.env_file changes
+func watchEnvFile(rootPath string) {
+	var last string
+	ticker := time.NewTicker(envFileInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		file := envFilePath(rootPath)
+		if file == "" || !tools.IsFile(file) {
+			last = ""
+			continue
+		}
+
+		hash, err := tools.FileHash(file)
+		if err != nil {
+			continue
+		}
+		current := fmt.Sprintf("%s:%x", file, hash)
+		if last != "" && current != last && canRestart() {
+			logger.Log.Infof("Env file %s changed, restarting...", conf.Run.EnvFile)
+			CmdRestart(rootPath)
+		}
+		last = current
+	}
+}
+
+// canRestart reports whether a build already started the app and no build is running,
+// which starts the app itself
+func canRestart() bool {
+	runMutex.RLock()
+	defer runMutex.RUnlock()
+	return appGeneration > 0 && !isBuilding
+}
@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/midoks/zzz/internal/diag"
+	"github.com/midoks/zzz/internal/logger"
+)
+
+// Driver builds and runs the projects of one language. The driver is picked
+// by the lang config key, or detected from the project files.
+type Driver interface {
+	// Name is the lang config value selecting the driver
+	Name() string
+	// Detect reports whether the project at rootPath uses the language
+	Detect(rootPath string) bool
+	// Defaults sets the language specific configuration defaults
+	Defaults(c *ZZZ)
+	// Build builds the project and reports failures, it returns false when
+	// the build failed or was cancelled
+	Build(ctx context.Context, rootPath string) bool
+	// Executable returns the program and arguments started after a build
+	Executable(rootPath string) (string, []string, error)
+	// ParseDiagnostics extracts diagnostics from build output
+	ParseDiagnostics(output string) []diag.Diagnostic
+}
+
+var (
+	// drivers are the built-in drivers in detection order
+	drivers = []Driver{
+		rustDriver{},
+		goDriver{},
+		zigDriver{},
+		cmakeDriver{},
+		makeDriver{},
+		nodeDriver{},
+	}
+
+	// customDrivers are defined by the languages config section
+	customDrivers []Driver
+	driverMutex   sync.RWMutex
+)
+
+// setCustomDrivers replaces the drivers defined in the configuration
+func setCustomDrivers(languages []Language) {
+	var list []Driver
+	for _, l := range languages {
+		if l.Name == "" {
+			logger.Log.Warn("Ignoring language without name")
+			continue
+		}
+		d, err := newCustomDriver(l)
+		if err != nil {
+			logger.Log.Warnf("Ignoring language '%s': %s", l.Name, err)
+			continue
+		}
+		list = append(list, d)
+	}
+
+	driverMutex.Lock()
+	customDrivers = list
+	driverMutex.Unlock()
+}
+
+// allDrivers returns the configured drivers followed by the built-in ones
+func allDrivers() []Driver {
+	driverMutex.RLock()
+	defer driverMutex.RUnlock()
+
+	list := make([]Driver, 0, len(customDrivers)+len(drivers))
+	list = append(list, customDrivers...)
+	return append(list, drivers...)
+}
+
+// lookupDriver returns the driver named name, or nil
+func lookupDriver(name string) Driver {
+	for _, d := range allDrivers() {
+		if strings.EqualFold(d.Name(), name) {
+			return d
+		}
+	}
+	return nil
+}
+
+// detectDriver returns the first driver recognising the project, or nil
+func detectDriver(rootPath string) Driver {
+	for _, d := range allDrivers() {
+		if d.Detect(rootPath) {
+			return d
+		}
+	}
+	return nil
+}
+
+// currentDriver returns the driver of the configured language, the detected
+// one when lang is unset or unknown, and the Go driver as last resort
+func currentDriver(rootPath string) Driver {
+	if d := lookupDriver(conf.Lang); d != nil {
+		return d
+	}
+	if d := detectDriver(rootPath); d != nil {
+		return d
+	}
+	return goDriver{}
+}
+
+// hasFile reports whether one of the files exists in dir
+func hasFile(dir string, files ...string) bool {
+	for _, f := range files {
+		if _, err := os.Stat(filepath.Join(dir, f)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// extInclude returns include rules watching the files with one of the
+// extensions, plus the files named exactly name at any depth. Drivers use it
+// for build files such as CMakeLists.txt, whose extension is too generic to
+// watch.
+func extInclude(ext []string, names ...string) []string {
+	rules := make([]string, 0, len(ext)+len(names))
+	for _, e := range ext {
+		rules = append(rules, "**/*."+e)
+	}
+	return append(rules, names...)
+}
+
+// buildCommands runs the build steps and then each command of a driver in
+// order, with build.cmd replacing them when set. Output is shown as it comes
+// and parsed into diagnostics by the driver.
+func buildCommands(ctx context.Context, rootPath string, d Driver, commands ...*exec.Cmd) bool {
+	if conf.Build.Cmd != "" {
+		commands = []*exec.Cmd{shellCommand(conf.Build.Cmd)}
+	}
+	return runBuild(ctx, rootPath, d.Name(), func() bool {
+		return runCommands(ctx, rootPath, d, commands)
+	})
+}
+
+// runCommands runs the build commands of a driver in order and stops at the
+// first failing one
+func runCommands(ctx context.Context, rootPath string, d Driver, commands []*exec.Cmd) bool {
+	if len(commands) == 0 {
+		return true
+	}
+
+	var output bytes.Buffer
+	for _, buildCmd := range commands {
+		logger.Log.Infof("Build command: %s", strings.Join(buildCmd.Args, " "))
+		buildCmd.Dir = rootPath
+		buildCmd.Env = buildEnv()
+		buildCmd.Stdout = io.MultiWriter(os.Stdout, &output)
+		buildCmd.Stderr = io.MultiWriter(os.Stderr, &output)
+
+		err := runCancelable(ctx, buildCmd)
+		if ctx.Err() != nil {
+			logger.Log.Warnf("%s build cancelled", d.Name())
+			return false
+		}
+
+		diags := d.ParseDiagnostics(output.String())
+		diag.SetLast(diags)
+		if err != nil {
+			reportBuildFailure(fmt.Sprintf("Build failed (%s)", err), output.String(), diags)
+			return false
+		}
+	}
+
+	logger.Log.Successf("%s build completed successfully", d.Name())
+	return true
+}
+
+// Language is a driver defined in the configuration. The project is detected
+// by one of the detect files, built with the build shell command and started
+// with the run shell command or run.bin.
+type Language struct {
+	Name        string   `yaml:"name"`
+	Detect      []string `yaml:"detect,omitempty"`
+	Ext         []string `yaml:"ext,omitempty"`
+	DirFilter   []string `yaml:"dirfilter,omitempty"`
+	Build       string   `yaml:"build,omitempty"`
+	Run         string   `yaml:"run,omitempty"`
+	ErrorFormat string   `yaml:"errorformat,omitempty"`
+}
+
+// customDriver is a driver defined by a Language config entry
+type customDriver struct {
+	lang    Language
+	pattern *regexp.Regexp
+}
+
+func newCustomDriver(l Language) (customDriver, error) {
+	d := customDriver{lang: l, pattern: diag.GCCPattern}
+	if l.ErrorFormat != "" {
+		re, err := regexp.Compile(l.ErrorFormat)
+		if err != nil {
+			return d, err
+		}
+		d.pattern = re
+	}
+	return d, nil
+}
+
+func (d customDriver) Name() string {
+	return d.lang.Name
+}
+
+func (d customDriver) Detect(rootPath string) bool {
+	return len(d.lang.Detect) > 0 && hasFile(rootPath, d.lang.Detect...)
+}
+
+func (d customDriver) Defaults(c *ZZZ) {
+	c.Lang = d.lang.Name
+	c.Ext = append([]string(nil), d.lang.Ext...)
+	c.DirFilter = append(c.DirFilter, d.lang.DirFilter...)
+}
+
+func (d customDriver) Build(ctx context.Context, rootPath string) bool {
+	if d.lang.Build == "" {
+		return buildCommands(ctx, rootPath, d)
+	}
+	return buildCommands(ctx, rootPath, d, shellCommand(d.lang.Build))
+}
+
+func (d customDriver) Executable(rootPath string) (string, []string, error) {
+	if d.lang.Run != "" {
+		name, args := shellScriptArgs(d.lang.Run)
+		return name, args, nil
+	}
+	return binExecutable(rootPath)
+}
+
+func (d customDriver) ParseDiagnostics(output string) []diag.Diagnostic {
+	return diag.ParsePattern(output, d.pattern)
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/midoks/zzz/internal/diag"
+)
+
+// goDriver builds Go modules with go build
+type goDriver struct{}
+
+func (goDriver) Name() string {
+	return "go"
+}
+
+func (goDriver) Detect(rootPath string) bool {
+	return hasFile(rootPath, "go.mod")
+}
+
+func (goDriver) Defaults(c *ZZZ) {
+	c.Lang = "go"
+	c.Ext = []string{"go"}
+	c.DirFilter = append(c.DirFilter, "vendor")
+}
+
+func (goDriver) Build(ctx context.Context, rootPath string) bool {
+	return CmdAutoBuild(ctx, rootPath)
+}
+
+func (goDriver) Executable(rootPath string) (string, []string, error) {
+	return goBinary(rootPath), nil, nil
+}
+
+func (goDriver) ParseDiagnostics(output string) []diag.Diagnostic {
+	return diag.ParseGo(output)
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/midoks/zzz/internal/diag"
+	"github.com/midoks/zzz/internal/tools"
+)
+
+// cSourceExt are the C and C++ file extensions watched by make and CMake projects
+var cSourceExt = []string{"c", "cc", "cpp", "cxx", "h", "hh", "hpp", "hxx"}
+
+// makeDriver builds C/C++ projects with make, run.bin names the program
+type makeDriver struct{}
+
+func (makeDriver) Name() string {
+	return "make"
+}
+
+func (makeDriver) Detect(rootPath string) bool {
+	return hasFile(rootPath, "Makefile", "makefile", "GNUmakefile")
+}
+
+func (makeDriver) Defaults(c *ZZZ) {
+	c.Lang = "make"
+	c.Ext = append(append([]string(nil), cSourceExt...), "mk")
+	c.Include = extInclude(c.Ext, "Makefile", "makefile", "GNUmakefile")
+	c.DirFilter = append(c.DirFilter, "build")
+}
+
+func (d makeDriver) Build(ctx context.Context, rootPath string) bool {
+	return buildCommands(ctx, rootPath, d, exec.Command("make", conf.Build.Flags...))
+}
+
+func (makeDriver) Executable(rootPath string) (string, []string, error) {
+	return binExecutable(rootPath)
+}
+
+func (makeDriver) ParseDiagnostics(output string) []diag.Diagnostic {
+	return diag.ParsePattern(output, diag.GCCPattern)
+}
+
+// cmakeDriver configures and builds CMake projects in the zzz artifact
+// directory
+type cmakeDriver struct{}
+
+var cmakeExecutable = regexp.MustCompile(`(?i)add_executable\s*\(\s*([A-Za-z0-9_.+-]+)`)
+
+func (cmakeDriver) Name() string {
+	return "cmake"
+}
+
+func (cmakeDriver) Detect(rootPath string) bool {
+	return hasFile(rootPath, "CMakeLists.txt")
+}
+
+func (cmakeDriver) Defaults(c *ZZZ) {
+	c.Lang = "cmake"
+	c.Ext = append(append([]string(nil), cSourceExt...), "cmake")
+	c.Include = extInclude(c.Ext, "CMakeLists.txt")
+	c.DirFilter = append(c.DirFilter, "build")
+}
+
+// buildDir returns the CMake binary directory
+func (cmakeDriver) buildDir(rootPath string) string {
+	return filepath.Join(artifactDir(rootPath), "cmake")
+}
+
+func (d cmakeDriver) Build(ctx context.Context, rootPath string) bool {
+	dir := d.buildDir(rootPath)
+
+	var commands []*exec.Cmd
+	if !tools.IsFile(filepath.Join(dir, "CMakeCache.txt")) {
+		commands = append(commands, exec.Command("cmake", "-S", rootPath, "-B", dir))
+	}
+	args := append([]string{"--build", dir}, conf.Build.Flags...)
+	commands = append(commands, exec.Command("cmake", args...))
+	return buildCommands(ctx, rootPath, d, commands...)
+}
+
+// Executable returns run.bin, a path or the name of a target, or the first
+// add_executable target of CMakeLists.txt inside the build directory
+func (d cmakeDriver) Executable(rootPath string) (string, []string, error) {
+	bin := conf.Run.Bin
+	if strings.ContainsAny(bin, `/\`) {
+		return localPath(bin), nil, nil
+	}
+
+	if bin == "" {
+		content, err := tools.ReadFile(filepath.Join(rootPath, "CMakeLists.txt"))
+		if err != nil {
+			return "", nil, err
+		}
+		m := cmakeExecutable.FindStringSubmatch(content)
+		if m == nil {
+			return "", nil, fmt.Errorf("no add_executable target found, set run.bin")
+		}
+		bin = m[1]
+	}
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+	return filepath.Join(d.buildDir(rootPath), bin), nil, nil
+}
+
+func (cmakeDriver) ParseDiagnostics(output string) []diag.Diagnostic {
+	return diag.ParsePattern(output, diag.GCCPattern)
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/midoks/zzz/internal/diag"
+)
+
+// nodeDriver runs Node.js and TypeScript projects. The build runs the build
+// script of package.json, or tsc for TypeScript projects without one, and
+// the app is started with the start script or the package main file.
+type nodeDriver struct{}
+
+// packageJSON is the subset of package.json used by the Node driver
+type packageJSON struct {
+	Main    string            `json:"main"`
+	Scripts map[string]string `json:"scripts"`
+}
+
+func readPackageJSON(rootPath string) packageJSON {
+	var p packageJSON
+	if b, err := os.ReadFile(filepath.Join(rootPath, "package.json")); err == nil {
+		json.Unmarshal(b, &p)
+	}
+	return p
+}
+
+func (nodeDriver) Name() string {
+	return "node"
+}
+
+func (nodeDriver) Detect(rootPath string) bool {
+	return hasFile(rootPath, "package.json")
+}
+
+func (nodeDriver) Defaults(c *ZZZ) {
+	c.Lang = "node"
+	c.Ext = []string{"js", "mjs", "cjs", "jsx", "ts", "mts", "cts", "tsx"}
+	// Other json files are data, e.g. fixtures or generated output
+	c.Include = extInclude(c.Ext, "package.json", "tsconfig.json")
+	c.DirFilter = append(c.DirFilter, "node_modules", "dist", "build", "coverage")
+}
+
+func (d nodeDriver) Build(ctx context.Context, rootPath string) bool {
+	if _, ok := readPackageJSON(rootPath).Scripts["build"]; ok {
+		return buildCommands(ctx, rootPath, d, exec.Command("npm", "run", "build"))
+	}
+	if hasFile(rootPath, "tsconfig.json") {
+		return buildCommands(ctx, rootPath, d, exec.Command("npx", "tsc"))
+	}
+	return buildCommands(ctx, rootPath, d)
+}
+
+// Executable returns run.bin, started with node when it is a script, the
+// npm start script or the package main file
+func (nodeDriver) Executable(rootPath string) (string, []string, error) {
+	if bin := conf.Run.Bin; bin != "" {
+		switch filepath.Ext(bin) {
+		case ".js", ".mjs", ".cjs":
+			return "node", []string{bin}, nil
+		}
+		return localPath(bin), nil, nil
+	}
+
+	p := readPackageJSON(rootPath)
+	if _, ok := p.Scripts["start"]; ok {
+		return "npm", []string{"start"}, nil
+	}
+	main := p.Main
+	if main == "" {
+		main = "index.js"
+	}
+	return "node", []string{strings.TrimPrefix(main, "./")}, nil
+}
+
+func (nodeDriver) ParseDiagnostics(output string) []diag.Diagnostic {
+	return diag.ParsePattern(output, diag.TSCPattern)
+}
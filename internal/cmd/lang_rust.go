@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/midoks/zzz/internal/diag"
+)
+
+// rustDriver builds Cargo packages and workspaces
+type rustDriver struct{}
+
+func (rustDriver) Name() string {
+	return "rust"
+}
+
+func (rustDriver) Detect(rootPath string) bool {
+	return hasFile(rootPath, "Cargo.toml")
+}
+
+func (rustDriver) Defaults(c *ZZZ) {
+	c.Lang = "rust"
+	c.Ext = []string{"rs"}
+	c.DirFilter = append(c.DirFilter, "target")
+	c.Rust.Profile = defaultRustProfile
+}
+
+func (rustDriver) Build(ctx context.Context, rootPath string) bool {
+	return CmdAutoBuildRust(ctx, rootPath)
+}
+
+func (rustDriver) Executable(rootPath string) (string, []string, error) {
+	bin, err := rustBinary(rootPath)
+	return bin, nil, err
+}
+
+func (rustDriver) ParseDiagnostics(output string) []diag.Diagnostic {
+	return diag.ParseCargo(output)
+}
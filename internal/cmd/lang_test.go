@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"os/exec"
+	"runtime"
+	"testing"
+)
+
+func TestCustomDriverRunArgs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cmd /C passes the arguments as they are")
+	}
+
+	tests := []struct {
+		run  string
+		args []string
+		want string
+	}{
+		{run: `echo ARGS: "$@"`, args: []string{"A", "B", "C"}, want: "ARGS: A B C\n"},
+		{run: `echo ARGS: "$@"`, want: "ARGS:\n"},
+		// Arguments reach the script unsplit and unexpanded
+		{run: `printf '[%s]' "$@"`, args: []string{"a b", "$HOME", `"q"`}, want: `[a b][$HOME]["q"]`},
+		{run: `echo one; echo two "$@"`, args: []string{"x"}, want: "one\ntwo x\n"},
+		// Scripts not using the arguments ignore them
+		{run: "echo plain", args: []string{"x"}, want: "plain\n"},
+		// A YAML block scalar ends in a newline
+		{run: "echo first\necho \"$@\"\n", args: []string{"--config", "x"}, want: "first\n--config x\n"},
+		{run: "echo \"$@\" # run the server", args: []string{"--config", "x"}, want: "--config x\n"},
+		{run: "echo \"$1\"\n# trailing comment\n", args: []string{"a", "b"}, want: "a\n"},
+	}
+
+	for _, tt := range tests {
+		d := customDriver{lang: Language{Name: "test", Run: tt.run}}
+		name, args, err := d.Executable(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := exec.Command(name, append(args, tt.args...)...).Output()
+		if err != nil {
+			t.Errorf("run %q: %s", tt.run, err)
+			continue
+		}
+		if string(out) != tt.want {
+			t.Errorf("run %q with %q printed %q, want %q", tt.run, tt.args, out, tt.want)
+		}
+	}
+}
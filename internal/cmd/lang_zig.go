@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/midoks/zzz/internal/diag"
+)
+
+// zigDriver builds Zig projects with zig build
+type zigDriver struct{}
+
+func (zigDriver) Name() string {
+	return "zig"
+}
+
+func (zigDriver) Detect(rootPath string) bool {
+	return hasFile(rootPath, "build.zig")
+}
+
+func (zigDriver) Defaults(c *ZZZ) {
+	c.Lang = "zig"
+	c.Ext = []string{"zig", "zon"}
+	c.DirFilter = append(c.DirFilter, "zig-out", "zig-cache", ".zig-cache")
+}
+
+func (d zigDriver) Build(ctx context.Context, rootPath string) bool {
+	args := append([]string{"build"}, conf.Build.Flags...)
+	return buildCommands(ctx, rootPath, d, exec.Command("zig", args...))
+}
+
+// Executable returns run.bin, a path or the name of an installed
+// executable, or the only executable installed into zig-out/bin
+func (zigDriver) Executable(rootPath string) (string, []string, error) {
+	dir := filepath.Join(rootPath, "zig-out", "bin")
+	if bin := conf.Run.Bin; bin != "" {
+		if strings.ContainsAny(bin, `/\`) {
+			return localPath(bin), nil, nil
+		}
+		return filepath.Join(dir, bin), nil, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	var bins []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			bins = append(bins, e.Name())
+		}
+	}
+	if len(bins) != 1 {
+		return "", nil, fmt.Errorf("found %d executables in %s, set run.bin", len(bins), dir)
+	}
+	return filepath.Join(dir, bins[0]), nil, nil
+}
+
+func (zigDriver) ParseDiagnostics(output string) []diag.Diagnostic {
+	return diag.ParsePattern(output, diag.GCCPattern)
+}
@@ -22,18 +22,78 @@ var New = cli.Command{
 // Note: struct fields must be public in order for unmarshal to
 // correctly populate the data.
 type ZZZ struct {
-	Dev       bool
-	Title     string
-	Frequency int64
-	DirFilter []string
-	Ext       []string
-	Lang      string
-	EnableRun bool
-	Action    struct {
+	Dev          bool
+	Title        string
+	Frequency    int64 `yaml:"frequency,omitempty"`
+	Debounce     int64
+	MaxWait      int64 `yaml:"max_wait"`
+	DirFilter    []string
+	Ext          []string
+	Include      []string
+	Exclude      []string
+	Gitignore    bool
+	Hash         bool
+	Watcher      string
+	PollInterval int64 `yaml:"poll_interval"`
+	Lang         string
+	Languages    []Language `yaml:"languages,omitempty"`
+	EnableRun    bool
+	Action       struct {
 		Before []string `yaml:"before"`
 		After  []string `yaml:"after"`
 		Exit   []string `yaml:"exit"`
 	}
+	Build struct {
+		Steps   []string `yaml:"steps,omitempty"`
+		Cmd     string   `yaml:"cmd,omitempty"`
+		Package string   `yaml:"package,omitempty"`
+		Output  string   `yaml:"output,omitempty"`
+
+		Tags     []string          `yaml:"tags,omitempty"`
+		Race     bool              `yaml:"race,omitempty"`
+		Trimpath bool              `yaml:"trimpath,omitempty"`
+		Gcflags  string            `yaml:"gcflags,omitempty"`
+		Ldflags  string            `yaml:"ldflags,omitempty"`
+		Flags    []string          `yaml:"flags,omitempty"`
+		Env      map[string]string `yaml:"env,omitempty"`
+	}
+	Rust struct {
+		Profile           string   `yaml:"profile,omitempty"`
+		Features          []string `yaml:"features,omitempty"`
+		NoDefaultFeatures bool     `yaml:"no_default_features,omitempty"`
+		Target            string   `yaml:"target,omitempty"`
+		Check             string   `yaml:"check,omitempty"`
+	}
+	Run struct {
+		Bin     string            `yaml:"bin,omitempty"`
+		Args    []string          `yaml:"args,omitempty"`
+		Env     map[string]string `yaml:"env,omitempty"`
+		EnvFile string            `yaml:"env_file,omitempty"`
+
+		Restart       string `yaml:"restart,omitempty"`
+		MaxRestarts   int    `yaml:"max_restarts,omitempty"`
+		RestartWindow int64  `yaml:"restart_window,omitempty"`
+		RestartDelay  int64  `yaml:"restart_delay,omitempty"`
+
+		StopSignal  string `yaml:"stop_signal,omitempty"`
+		StopTimeout int64  `yaml:"stop_timeout,omitempty"`
+		KillGroup   *bool  `yaml:"kill_group,omitempty"`
+
+		Ready []Probe `yaml:"ready,omitempty"`
+
+		Port        int      `yaml:"port,omitempty"`
+		PortTimeout int64    `yaml:"port_timeout,omitempty"`
+		Listen      []string `yaml:"listen,omitempty"`
+	}
+	Proxy struct {
+		Listen  string `yaml:"listen,omitempty"`
+		Target  string `yaml:"target,omitempty"`
+		Timeout int64  `yaml:"timeout,omitempty"`
+	}
+	Diagnostics struct {
+		File   string `yaml:"file,omitempty"`
+		Format string `yaml:"format,omitempty"`
+	}
 	Link string
 }
 
@@ -54,51 +114,32 @@ func CmdNew(c *cli.Context) error {
 
 		conf := ZZZ{}
 
-		if tools.IsRustP() {
-			conf.Title = "zzz"
-			conf.Lang = "rust"
-			conf.Ext = append(conf.Ext, "rs")
-			conf.Frequency = 3
-			conf.EnableRun = true
-			conf.Dev = true
-
-			conf.DirFilter = append(conf.DirFilter, "tmp")
-			conf.DirFilter = append(conf.DirFilter, ".git")
-			conf.DirFilter = append(conf.DirFilter, "public")
-			conf.DirFilter = append(conf.DirFilter, "scripts")
-			conf.DirFilter = append(conf.DirFilter, "target")
-			conf.DirFilter = append(conf.DirFilter, "logs")
-			conf.DirFilter = append(conf.DirFilter, "templates")
-
-			conf.Action.Before = append(conf.Action.Before, "echo \"zzz start\"")
-			conf.Action.After = append(conf.Action.After, "echo \"zzz end\"")
-			conf.Action.Exit = append(conf.Action.Exit, "echo \"exit\"")
-			conf.Link = "https://github.com/midoks/zzz"
-
-		} else {
-
-			conf.Title = "zzz"
-			conf.Lang = "go"
-			conf.Ext = append(conf.Ext, "go")
-			conf.Frequency = 3
-			conf.EnableRun = true
-			conf.Dev = true
-
-			conf.DirFilter = append(conf.DirFilter, "tmp")
-			conf.DirFilter = append(conf.DirFilter, ".git")
-			conf.DirFilter = append(conf.DirFilter, "public")
-			conf.DirFilter = append(conf.DirFilter, "scripts")
-			conf.DirFilter = append(conf.DirFilter, "vendor")
-			conf.DirFilter = append(conf.DirFilter, "logs")
-			conf.DirFilter = append(conf.DirFilter, "templates")
-
-			conf.Action.Before = append(conf.Action.Before, "echo \"zzz start\"")
-			conf.Action.After = append(conf.Action.After, "echo \"zzz end\"")
-			conf.Action.Exit = append(conf.Action.Exit, "echo \"exit\"")
-			conf.Link = "https://github.com/midoks/zzz"
-
+		driver := detectDriver(rootPath)
+		if driver == nil {
+			driver = goDriver{}
 		}
 
+		conf.Title = "zzz"
+		conf.Debounce = defaultDebounce
+		conf.MaxWait = defaultMaxWait
+		conf.Watcher = WatcherAuto
+		conf.PollInterval = defaultPollInterval
+		conf.EnableRun = true
+		conf.Dev = true
+
+		conf.DirFilter = append(conf.DirFilter, "tmp")
+		conf.DirFilter = append(conf.DirFilter, ".git")
+		conf.DirFilter = append(conf.DirFilter, "public")
+		conf.DirFilter = append(conf.DirFilter, "scripts")
+		conf.DirFilter = append(conf.DirFilter, "logs")
+		conf.DirFilter = append(conf.DirFilter, "templates")
+		driver.Defaults(&conf)
+
+		conf.Action.Before = append(conf.Action.Before, "echo \"zzz start\"")
+		conf.Action.After = append(conf.Action.After, "echo \"zzz end\"")
+		conf.Action.Exit = append(conf.Action.Exit, "echo \"exit\"")
+		conf.Link = "https://github.com/midoks/zzz"
+
 		d, err := yaml.Marshal(&conf)
 		if err != nil {
 			fmt.Println("create configuration file fail!")
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/midoks/zzz/internal/logger"
+	"github.com/midoks/zzz/internal/matcher"
+	"github.com/midoks/zzz/internal/tools"
+)
+
+const defaultPollInterval = 1000
+
+var (
+	// Last seen state of the .gitignore/.ignore files, polling has no
+	// notification telling the matchers to re-read them
+	ignoreFiles = make(map[string]fileCacheEntry)
+	ignoreMutex sync.Mutex
+)
+
+// startPolling watches every watch root by scanning the tree periodically and
+// comparing every file against fileCache. It works on file systems that do
+// not deliver change notifications, such as NFS, SSHFS or WSL shares.
+func startPolling(changes *changeSet) {
+	// fileCache is seeded by initWatcher, catch up on anything changed since
+	rescan(changes)
+
+	logger.Log.Successf("Polling watcher initialized, scanning every %dms", pollInterval())
+
+	go func() {
+		for {
+			time.Sleep(time.Duration(pollInterval()) * time.Millisecond)
+
+			rescan(changes)
+		}
+	}()
+}
+
+// rescan compares the watched trees against fileCache and records every file
+// changed, created or removed since it was last seen. It returns the number
+// of files recorded.
+func rescan(changes *changeSet) int {
+	count := 0
+	for _, root := range getWatchRoots() {
+		files, ignores := scanFiles(root)
+		if updateIgnoreFiles(root, ignores) {
+			// The ignore rules changed, filter the tree again with the new ones
+			if m := getMatcher(root); m != nil {
+				m.Reset()
+			}
+			files, _ = scanFiles(root)
+		}
+
+		seen := make(map[string]bool, len(files))
+		for _, name := range files {
+			seen[name] = true
+			if hasFileChanged(name) {
+				queueChange("Changed", name, changes)
+				count++
+			}
+		}
+
+		// Files that vanished since the last scan were removed or renamed
+		for _, name := range cachedFiles(root) {
+			if seen[name] {
+				continue
+			}
+			forgetFile(name)
+			// Still there, but no longer watched after an ignore file change
+			if tools.IsFile(name) {
+				continue
+			}
+			queueChange("Removed", name, changes)
+			count++
+		}
+	}
+	return count
+}
+
+// seedFileCache records the current state of every watched file in fileCache
+// and returns the number of files seen
+func seedFileCache() int {
+	count := 0
+	for _, root := range getWatchRoots() {
+		files, ignores := scanFiles(root)
+		updateIgnoreFiles(root, ignores)
+		for _, name := range files {
+			hasFileChanged(name)
+		}
+		count += len(files)
+	}
+	return count
+}
+
+// scanFiles returns every watched file below rootPath, and the ignore files
+// found along the way
+func scanFiles(rootPath string) ([]string, []string) {
+	var files, ignores []string
+	for _, dir := range tools.GetPathDirFunc(rootPath, isFilterDir) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			name := dir + "/" + entry.Name()
+			if matcher.IsIgnoreFile(name) {
+				ignores = append(ignores, name)
+			}
+			if !isFilterFile(name) {
+				files = append(files, name)
+			}
+		}
+	}
+	return files, ignores
+}
+
+// updateIgnoreFiles records the state of the ignore files below rootPath and
+// reports whether one was created, changed or removed since the last scan
+func updateIgnoreFiles(rootPath string, names []string) bool {
+	ignoreMutex.Lock()
+	defer ignoreMutex.Unlock()
+
+	changed := false
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		seen[name] = true
+		fi, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		old, ok := ignoreFiles[name]
+		if !ok || !fi.ModTime().Equal(old.modTime) || fi.Size() != old.size {
+			ignoreFiles[name] = fileCacheEntry{modTime: fi.ModTime(), size: fi.Size(), cachedAt: time.Now()}
+			changed = true
+		}
+	}
+
+	for name := range ignoreFiles {
+		if strings.HasPrefix(name, rootPath+"/") && !seen[name] {
+			delete(ignoreFiles, name)
+			changed = true
+		}
+	}
+	return changed
+}
+
+func pollInterval() int64 {
+	runMutex.RLock()
+	defer runMutex.RUnlock()
+	return conf.PollInterval
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/midoks/zzz/internal/logger"
+	"github.com/midoks/zzz/internal/netstat"
+)
+
+const defaultPortTimeout = 5000 // milliseconds
+
+// waitPort waits for run.port to be released by the previous app instance,
+// unless zzz owns the port through run.listen.
+// It returns false, naming the processes still listening when known, if the
+// port stays in use.
+func waitPort() bool {
+	port := conf.Run.Port
+	if port <= 0 || ownsPort(port) || netstat.PortFree(port) {
+		return true
+	}
+
+	timeout := time.Duration(conf.Run.PortTimeout) * time.Millisecond
+	logger.Log.Infof("Waiting for port %d to be released...", port)
+	start := time.Now()
+	if netstat.WaitPortFree(port, timeout) {
+		logger.Log.Infof("Port %d released after %s", port, time.Since(start).Round(time.Millisecond))
+		return true
+	}
+
+	procs, err := netstat.Listeners(port)
+	switch {
+	case err != nil:
+		logger.Log.Errorf("Port %d is still in use after %s", port, timeout)
+	case len(procs) == 0:
+		logger.Log.Errorf("Port %d is still in use after %s, no listening process found", port, timeout)
+	default:
+		for _, p := range procs {
+			logger.Log.Errorf("Port %d is still in use after %s by %s", port, timeout, p)
+		}
+	}
+	return false
+}
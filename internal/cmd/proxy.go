@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/midoks/zzz/internal/logger"
+	"github.com/midoks/zzz/internal/proxy"
+)
+
+const defaultProxyTimeout = 10000 // milliseconds
+
+// devProxy is the live reload proxy, nil unless proxy.listen is set
+var devProxy *proxy.Server
+
+// startProxy starts the live reload proxy in front of the app
+func startProxy() {
+	if conf.Proxy.Listen == "" {
+		return
+	}
+
+	target := conf.Proxy.Target
+	if target == "" && conf.Run.Port > 0 {
+		target = "localhost:" + strconv.Itoa(conf.Run.Port)
+	}
+	if target == "" {
+		logger.Log.Error("Proxy disabled, set proxy.target or run.port")
+		return
+	}
+
+	s, err := proxy.New(target, time.Duration(conf.Proxy.Timeout)*time.Millisecond)
+	if err != nil {
+		logger.Log.Errorf("Proxy disabled: %s", err)
+		return
+	}
+	devProxy = s
+
+	go func() {
+		if err := s.ListenAndServe(conf.Proxy.Listen); err != nil {
+			logger.Log.Errorf("Proxy stopped: %s", err)
+		}
+	}()
+	logger.Log.Infof("Live reload proxy on %s -> %s", conf.Proxy.Listen, target)
+}
+
+// pauseProxy holds proxied requests while the app is down
+func pauseProxy() {
+	if devProxy != nil {
+		devProxy.Pause()
+	}
+}
+
+// reloadProxy releases held requests and reloads connected browsers
+func reloadProxy() {
+	if devProxy == nil {
+		return
+	}
+	if n := devProxy.Clients(); n > 0 {
+		logger.Log.Infof("Reloading %d browser(s)", n)
+	}
+	devProxy.Ready()
+}
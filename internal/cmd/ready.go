@@ -0,0 +1,310 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/midoks/zzz/internal/logger"
+)
+
+const (
+	defaultProbeTimeout = 10000 // milliseconds
+	probeInterval       = 100 * time.Millisecond
+	// maxLogLine bounds the output buffered while waiting for a newline
+	maxLogLine = 64 * 1024
+)
+
+// Probe is a readiness check of the started app. Exactly one of TCP, HTTP,
+// Log and Cmd is set.
+type Probe struct {
+	// TCP is an address that accepts connections once the app is ready
+	TCP string `yaml:"tcp,omitempty"`
+	// HTTP is a URL answering GET requests with Status
+	HTTP   string `yaml:"http,omitempty"`
+	Status int    `yaml:"status,omitempty"`
+	// Log is a regular expression matching an output line of the app
+	Log string `yaml:"log,omitempty"`
+	// Cmd is a shell command exiting with status 0
+	Cmd string `yaml:"cmd,omitempty"`
+	// Timeout in milliseconds
+	Timeout int64 `yaml:"timeout,omitempty"`
+}
+
+// errAppExited stops probing an app that is no longer running
+var errAppExited = errors.New("application exited")
+
+var (
+	// readyGeneration is the appGeneration of the last app that became ready
+	readyGeneration uint64
+)
+
+// String describes the probe for logs
+func (p Probe) String() string {
+	switch {
+	case p.TCP != "":
+		return "tcp " + p.TCP
+	case p.HTTP != "":
+		return fmt.Sprintf("http %s (status %d)", p.HTTP, p.Status)
+	case p.Log != "":
+		return "log /" + p.Log + "/"
+	}
+	return "cmd " + p.Cmd
+}
+
+// validateProbes drops invalid readiness probes and sets their defaults
+func validateProbes() {
+	var probes []Probe
+	for _, p := range conf.Run.Ready {
+		kinds := 0
+		for _, v := range []string{p.TCP, p.HTTP, p.Log, p.Cmd} {
+			if v != "" {
+				kinds++
+			}
+		}
+		if kinds != 1 {
+			logger.Log.Warn("Ignoring readiness probe, set exactly one of tcp, http, log and cmd")
+			continue
+		}
+		if p.Log != "" {
+			if _, err := regexp.Compile(p.Log); err != nil {
+				logger.Log.Warnf("Ignoring readiness probe: %s", err)
+				continue
+			}
+		}
+		if p.TCP != "" && ownsAddr(p.TCP) {
+			// zzz accepts into the backlog of its own socket, the probe would
+			// pass at once and leave a connection in the app's accept queue
+			logger.Log.Warnf("Ignoring tcp readiness probe on %s, zzz holds that socket (run.listen), use an http or log probe", p.TCP)
+			continue
+		}
+		if p.HTTP != "" && p.Status == 0 {
+			p.Status = http.StatusOK
+		}
+		if p.Timeout <= 0 {
+			p.Timeout = defaultProbeTimeout
+		}
+		probes = append(probes, p)
+	}
+	conf.Run.Ready = probes
+}
+
+// awaitReady runs the readiness probes of a started app in order, then
+// reports the startup latency and signals the started channel. Without
+// probes the app counts as ready once it survived its first moments. probes
+// are those the app was started with, logs waits for their patterns.
+func awaitReady(rootPath, name string, gen uint64, done chan struct{}, startedAt time.Time, probes []Probe, logs *logWaiters) {
+	if len(probes) == 0 {
+		select {
+		case <-done:
+			return
+		case <-time.After(probeInterval):
+		}
+		logger.Log.Successf("'%s' is running...", name)
+		markReady(gen, false)
+		return
+	}
+
+	for _, p := range probes {
+		if err := runProbe(rootPath, p, done, logs); err != nil {
+			if err == errAppExited {
+				return
+			}
+			logger.Log.Errorf("'%s' started but never became ready: %s", name, err)
+			return
+		}
+	}
+
+	logger.Log.Successf("'%s' is ready in %s", name, time.Since(startedAt).Round(time.Millisecond))
+	markReady(gen, true)
+}
+
+// markReady records a ready app. Passing the probes proves a healthy start,
+// so the crash restart accounting starts over.
+func markReady(gen uint64, probed bool) {
+	runMutex.Lock()
+	if gen != appGeneration {
+		runMutex.Unlock()
+		return
+	}
+	readyGeneration = gen
+	if probed {
+		restartTimes = nil
+	}
+	runMutex.Unlock()
+
+	reloadProxy()
+
+	// Non-blocking send to started channel
+	select {
+	case started <- true:
+	default:
+	}
+}
+
+// isReady reports whether the app instance gen became ready
+func isReady(gen uint64) bool {
+	runMutex.RLock()
+	defer runMutex.RUnlock()
+	return readyGeneration == gen
+}
+
+// runProbe retries a probe until it passes, its timeout expires or the app exits
+func runProbe(rootPath string, p Probe, done chan struct{}, logs *logWaiters) error {
+	timeout := time.Duration(p.Timeout) * time.Millisecond
+	deadline := time.After(timeout)
+
+	var matched chan struct{}
+	if p.Log != "" {
+		if matched = logs.wait(p.Log); matched == nil {
+			return fmt.Errorf("%s: output of the app is not matched against it", p)
+		}
+	}
+
+	for {
+		var err error
+		if matched != nil {
+			select {
+			case <-matched:
+				return nil
+			case <-done:
+				return errAppExited
+			case <-deadline:
+				return fmt.Errorf("%s: no matching line within %s", p, timeout)
+			}
+		}
+
+		if err = checkProbe(rootPath, p); err == nil {
+			return nil
+		}
+
+		select {
+		case <-done:
+			return errAppExited
+		case <-deadline:
+			return fmt.Errorf("%s: not ready within %s: %s", p, timeout, err)
+		case <-time.After(probeInterval):
+		}
+	}
+}
+
+// checkProbe runs a tcp, http or cmd probe once
+func checkProbe(rootPath string, p Probe) error {
+	switch {
+	case p.TCP != "":
+		conn, err := net.DialTimeout("tcp", p.TCP, time.Second)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+
+	case p.HTTP != "":
+		client := http.Client{Timeout: 2 * time.Second}
+		resp, err := client.Get(p.HTTP)
+		if err != nil {
+			return err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != p.Status {
+			return fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(p.Timeout)*time.Millisecond)
+	defer cancel()
+
+	c := shellCommand(p.Cmd)
+	c.Dir = rootPath
+	c.Env = runEnv(rootPath)
+	return runCancelable(ctx, c)
+}
+
+// logWaiters matches the output lines of the app against the patterns of
+// the log probes. They are registered before the app starts so no early
+// line is missed.
+type logWaiters struct {
+	mutex   sync.Mutex
+	waiters map[string]*logWaiter
+}
+
+type logWaiter struct {
+	re      *regexp.Regexp
+	matched chan struct{}
+	done    bool
+}
+
+func newLogWaiters(probes []Probe) *logWaiters {
+	w := &logWaiters{waiters: make(map[string]*logWaiter)}
+	for _, p := range probes {
+		if p.Log != "" {
+			w.waiters[p.Log] = &logWaiter{re: regexp.MustCompile(p.Log), matched: make(chan struct{})}
+		}
+	}
+	return w
+}
+
+// wait returns a channel closed once a line matches pattern, or nil when
+// pattern was not registered
+func (w *logWaiters) wait(pattern string) chan struct{} {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if lw, ok := w.waiters[pattern]; ok {
+		return lw.matched
+	}
+	return nil
+}
+
+func (w *logWaiters) match(line []byte) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, lw := range w.waiters {
+		if !lw.done && lw.re.Match(line) {
+			lw.done = true
+			close(lw.matched)
+		}
+	}
+}
+
+// tap returns a writer passing output through to out while matching its lines
+func (w *logWaiters) tap(out io.Writer) io.Writer {
+	if len(w.waiters) == 0 {
+		return out
+	}
+	return &logTap{out: out, waiters: w}
+}
+
+// logTap splits one output stream of the app into lines for logWaiters
+type logTap struct {
+	out     io.Writer
+	waiters *logWaiters
+	pending []byte
+}
+
+func (t *logTap) Write(p []byte) (int, error) {
+	n, err := t.out.Write(p)
+
+	t.pending = append(t.pending, p...)
+	for {
+		i := bytes.IndexByte(t.pending, '\n')
+		if i < 0 {
+			break
+		}
+		t.waiters.match(t.pending[:i])
+		t.pending = t.pending[i+1:]
+	}
+	// Output without newlines is matched in chunks instead of piling up
+	if len(t.pending) > maxLogLine {
+		t.waiters.match(t.pending)
+		t.pending = nil
+	}
+	return n, err
+}
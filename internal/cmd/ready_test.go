@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLogWaiters(t *testing.T) {
+	w := newLogWaiters([]Probe{{Log: "listening on :\\d+"}, {Log: "^tail$"}, {HTTP: "http://localhost"}})
+	if w.wait("not registered") != nil {
+		t.Error("wait() on an unknown pattern returned a channel")
+	}
+
+	var out bytes.Buffer
+	tap := w.tap(&out)
+	io.WriteString(tap, "starting\nlisten")
+	io.WriteString(tap, "ing on :8080\n")
+
+	select {
+	case <-w.wait("listening on :\\d+"):
+	default:
+		t.Error("line split across writes did not match")
+	}
+	if out.String() != "starting\nlistening on :8080\n" {
+		t.Errorf("output passed through = %q", out.String())
+	}
+
+	// Output without newline is not buffered without bound
+	io.WriteString(tap, strings.Repeat("x", maxLogLine+1))
+	if pending := len(tap.(*logTap).pending); pending > maxLogLine {
+		t.Errorf("pending output = %d bytes, want at most %d", pending, maxLogLine)
+	}
+	io.WriteString(tap, "tail\n")
+	select {
+	case <-w.wait("^tail$"):
+	default:
+		t.Error("line after a flushed chunk did not match")
+	}
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/midoks/zzz/internal/logger"
+)
+
+// Restart policies of run.restart
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+const (
+	defaultMaxRestarts   = 5
+	defaultRestartWindow = 60    // seconds
+	defaultRestartDelay  = 500   // milliseconds
+	maxRestartDelay      = 30000 // milliseconds
+)
+
+var (
+	// appGeneration changes whenever the app is started or stopped by zzz,
+	// so an exit can be told apart from a crash of the current instance
+	appGeneration uint64
+	// appDone is closed when the current app instance exits
+	appDone chan struct{}
+	// restartTimes are the automatic restarts within the restart window
+	restartTimes []time.Time
+)
+
+// waitApp waits for an app instance to exit, reports how it ended and
+// applies the restart policy when it was not stopped by zzz
+func waitApp(rootPath string, c *exec.Cmd, gen uint64, done chan struct{}, startedAt time.Time) {
+	err := c.Wait()
+	close(done)
+
+	runMutex.Lock()
+	current := gen == appGeneration
+	if current {
+		cmd = nil
+	}
+	runMutex.Unlock()
+
+	uptime := time.Since(startedAt).Round(time.Millisecond)
+	desc := exitDescription(c.ProcessState, err)
+	if !current {
+		logger.Log.Infof("Application stopped (%s)", desc)
+		return
+	}
+
+	pauseProxy()
+
+	// An app exiting before it became ready failed to start
+	failed := err != nil || (len(conf.Run.Ready) > 0 && !isReady(gen))
+	if err != nil {
+		logger.Log.Errorf("Application exited with %s after %s", desc, uptime)
+	} else if failed {
+		logger.Log.Errorf("Application exited before becoming ready after %s", uptime)
+	} else {
+		logger.Log.Infof("Application exited normally after %s", uptime)
+	}
+
+	switch conf.Run.Restart {
+	case RestartAlways:
+	case RestartOnFailure:
+		if !failed {
+			return
+		}
+	default:
+		return
+	}
+
+	delay, ok := nextRestart()
+	if !ok {
+		logger.Log.Errorf("Crash loop detected: restarted %d times within %ds, waiting for the next change",
+			conf.Run.MaxRestarts, conf.Run.RestartWindow)
+		return
+	}
+
+	logger.Log.Warnf("Restarting application in %s (run.restart: %s)", delay, conf.Run.Restart)
+	time.AfterFunc(delay, func() {
+		if !waitPort() {
+			return
+		}
+
+		runMutex.Lock()
+		defer runMutex.Unlock()
+
+		// A build or another restart may have taken over meanwhile
+		startApp(rootPath, gen)
+	})
+}
+
+// nextRestart records an automatic restart and returns its backoff delay,
+// which doubles with each restart inside the window. It returns false when
+// the maximum number of restarts within the window was reached.
+func nextRestart() (time.Duration, bool) {
+	runMutex.Lock()
+	defer runMutex.Unlock()
+
+	window := time.Duration(conf.Run.RestartWindow) * time.Second
+	now := time.Now()
+
+	recent := restartTimes[:0]
+	for _, t := range restartTimes {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	restartTimes = recent
+
+	if len(restartTimes) >= conf.Run.MaxRestarts {
+		return 0, false
+	}
+
+	delay := conf.Run.RestartDelay << uint(len(restartTimes))
+	if delay > maxRestartDelay || delay <= 0 {
+		delay = maxRestartDelay
+	}
+	restartTimes = append(restartTimes, now)
+	return time.Duration(delay) * time.Millisecond, true
+}
+
+// resetRestarts clears the restart accounting, a new build starts afresh
+func resetRestarts() {
+	runMutex.Lock()
+	restartTimes = nil
+	runMutex.Unlock()
+}
+
+// exitDescription describes how a process ended: its exit code or the
+// signal that terminated it
+func exitDescription(state *os.ProcessState, err error) string {
+	if state == nil {
+		if err != nil {
+			return err.Error()
+		}
+		return "unknown status"
+	}
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return fmt.Sprintf("signal %s", ws.Signal())
+	}
+	return fmt.Sprintf("exit code %d", state.ExitCode())
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -19,10 +20,11 @@ import (
 	"github.com/urfave/cli"
 	"gopkg.in/yaml.v2"
 
-	"github.com/fsnotify/fsnotify"
+	"github.com/midoks/zzz/internal/diag"
 	"github.com/midoks/zzz/internal/hotreload"
 	"github.com/midoks/zzz/internal/logger"
 	"github.com/midoks/zzz/internal/logger/colors"
+	"github.com/midoks/zzz/internal/matcher"
 	"github.com/midoks/zzz/internal/monitor"
 	"github.com/midoks/zzz/internal/optimizer"
 	"github.com/midoks/zzz/internal/tools"
@@ -31,10 +33,22 @@ import (
 var Run = cli.Command{
 	Name:        "run",
 	Usage:       "Run the application",
-	Description: `Run the application by starting a local development server`,
+	Description: `Run the application by starting a local development server, arguments after "--" are passed to the application`,
 	Action:      CmdRun,
 	Flags: []cli.Flag{
 		stringFlag("ldflags, ld", "", "Set the build ldflags. See: https://golang.org/pkg/go/build/"),
+		stringFlag("gcflags", "", "Set the build gcflags, overrides build.gcflags"),
+		stringFlag("tags", "", "Set comma-separated build tags, overrides build.tags"),
+		boolFlag("race", "Enable the race detector"),
+		boolFlag("trimpath", "Remove file system paths from the binary"),
+		cli.StringSliceFlag{
+			Name:  "env, e",
+			Usage: "Set a build environment variable (KEY=VALUE), can be repeated",
+		},
+		stringFlag("build-cmd", "", "Set a custom build command, overrides build.cmd"),
+		stringFlag("package, p", "", "Set the package to build, overrides build.package"),
+		stringFlag("output, o", "", "Set the build output path, overrides build.output"),
+		stringFlag("bin", "", "Set the executable to run, overrides run.bin"),
 	},
 }
 
@@ -42,14 +56,19 @@ var (
 	runMutex       sync.RWMutex
 	conf           *ZZZ
 	cmd            *exec.Cmd
-	buildLDFlags   string
+	flagOverrides  = make(map[string]string)
+	envOverrides   []string
 	eventTime      = make(map[string]int64)
 	started        = make(chan bool, 1)
 	isBuilding     = false
+	buildFailure   string
+	exiting        bool
 	fileCache      = make(map[string]fileCacheEntry)
 	cacheMutex     sync.RWMutex
 	perfOptimizer  *optimizer.Optimizer
 	configReloader *hotreload.ConfigReloader
+	fileMatchers   = make(map[string]*matcher.Matcher)
+	matcherMutex   sync.RWMutex
 	// Performance optimization: reduce memory allocations
 	stringPool = sync.Pool{
 		New: func() interface{} {
@@ -63,6 +82,8 @@ type fileCacheEntry struct {
 	modTime  time.Time
 	size     int64
 	cachedAt time.Time
+	hash     uint64
+	hashed   bool
 }
 
 func init() {
@@ -117,52 +138,185 @@ func init() {
 }
 
 func setDefaultConfig() {
-	if tools.IsRustP() {
-		conf.DirFilter = []string{".git", ".github", "target", ".DS_Store", "tmp", ".bak", ".chk"}
-		conf.Ext = []string{"rs"}
-		conf.Lang = "rust"
-		conf.Frequency = 3
-		conf.Dev = false
-		conf.EnableRun = true
-	} else {
-		conf.DirFilter = []string{".git", ".github", "vendor", ".DS_Store", "tmp", ".bak", ".chk"}
-		conf.Ext = []string{"go"}
-		conf.Lang = "go"
-		conf.Frequency = 3
-		conf.Dev = false
-		conf.EnableRun = true
+	rootPath, _ := os.Getwd()
+	d := detectDriver(rootPath)
+	if d == nil {
+		d = goDriver{}
 	}
+
+	conf.DirFilter = []string{".git", ".github", ".DS_Store", "tmp", ".bak", ".chk"}
+	d.Defaults(conf)
+	conf.Debounce = defaultDebounce
+	conf.MaxWait = defaultMaxWait
+	conf.Watcher = WatcherAuto
+	conf.PollInterval = defaultPollInterval
+	conf.Dev = false
+	conf.EnableRun = true
+	conf.Run.Restart = RestartNever
+	conf.Run.StopSignal = defaultStopSignal
+	conf.Run.StopTimeout = defaultStopTimeout
 }
 
 func validateConfig() {
-	// Ensure frequency is reasonable
-	if conf.Frequency < 1 {
-		logger.Log.Warn("Frequency too low, setting to 1 second")
-		conf.Frequency = 1
+	// Ensure frequency is reasonable, it is only kept for older configurations
+	if conf.Frequency < 0 {
+		logger.Log.Warn("Frequency too low, ignoring it")
+		conf.Frequency = 0
 	} else if conf.Frequency > 60 {
 		logger.Log.Warn("Frequency too high, setting to 60 seconds")
 		conf.Frequency = 60
 	}
 
-	// Ensure we have file extensions to watch
-	if len(conf.Ext) == 0 {
-		logger.Log.Warn("No file extensions specified, using defaults")
-		if conf.Lang == "rust" {
-			conf.Ext = []string{"rs"}
+	// The quiet period falls back to the legacy frequency (in seconds)
+	if conf.Debounce <= 0 {
+		if conf.Frequency > 0 {
+			conf.Debounce = conf.Frequency * 1000
 		} else {
-			conf.Ext = []string{"go"}
+			conf.Debounce = defaultDebounce
 		}
 	}
+	if conf.MaxWait <= 0 {
+		conf.MaxWait = defaultMaxWait
+	}
+	if conf.MaxWait < conf.Debounce {
+		logger.Log.Warnf("max_wait is shorter than debounce, setting it to %dms", conf.Debounce)
+		conf.MaxWait = conf.Debounce
+	}
+
+	// Ensure the watcher backend is known
+	switch conf.Watcher {
+	case WatcherAuto, WatcherNotify, WatcherPoll:
+	case "":
+		conf.Watcher = WatcherAuto
+	default:
+		logger.Log.Warnf("Unknown watcher '%s', using '%s'", conf.Watcher, WatcherAuto)
+		conf.Watcher = WatcherAuto
+	}
+
+	if conf.PollInterval <= 0 {
+		conf.PollInterval = defaultPollInterval
+	} else if conf.PollInterval < 100 {
+		logger.Log.Warn("Poll interval too low, setting to 100 milliseconds")
+		conf.PollInterval = 100
+	}
 
 	// Ensure language is set
+	rootPath, _ := os.Getwd()
+	setCustomDrivers(conf.Languages)
+	if conf.Lang != "" && lookupDriver(conf.Lang) == nil {
+		logger.Log.Warnf("Unknown language '%s', detecting it", conf.Lang)
+		conf.Lang = ""
+	}
 	if conf.Lang == "" {
-		if tools.IsRustP() {
-			conf.Lang = "rust"
-		} else {
-			conf.Lang = "go"
-		}
+		conf.Lang = currentDriver(rootPath).Name()
 		logger.Log.Infof("Language auto-detected: %s", conf.Lang)
 	}
+
+	// Ensure we have file extensions to watch
+	if len(conf.Ext) == 0 && len(conf.Include) == 0 {
+		logger.Log.Warn("No file extensions specified, using defaults")
+		defaults := new(ZZZ)
+		lookupDriver(conf.Lang).Defaults(defaults)
+		conf.Ext = defaults.Ext
+		conf.Include = defaults.Include
+	}
+
+	if conf.Diagnostics.Format == "" {
+		conf.Diagnostics.Format = diag.FormatQuickfix
+	} else if !diag.IsFormat(conf.Diagnostics.Format) {
+		logger.Log.Warnf("Unknown diagnostics format '%s', using '%s'", conf.Diagnostics.Format, diag.FormatQuickfix)
+		conf.Diagnostics.Format = diag.FormatQuickfix
+	}
+
+	// Restart policy
+	switch conf.Run.Restart {
+	case "":
+		conf.Run.Restart = RestartNever
+	case RestartNever, RestartOnFailure, RestartAlways:
+	default:
+		logger.Log.Warnf("Unknown run.restart '%s', using '%s'", conf.Run.Restart, RestartNever)
+		conf.Run.Restart = RestartNever
+	}
+	if conf.Run.MaxRestarts <= 0 {
+		conf.Run.MaxRestarts = defaultMaxRestarts
+	}
+	if conf.Run.RestartWindow <= 0 {
+		conf.Run.RestartWindow = defaultRestartWindow
+	}
+	if conf.Run.RestartDelay <= 0 {
+		conf.Run.RestartDelay = defaultRestartDelay
+	}
+
+	validateProbes()
+
+	// Shutdown
+	if conf.Run.StopSignal == "" {
+		conf.Run.StopSignal = defaultStopSignal
+	} else if _, err := parseSignal(conf.Run.StopSignal); err != nil {
+		logger.Log.Warnf("Invalid run.stop_signal: %s, using %s", err, defaultStopSignal)
+		conf.Run.StopSignal = defaultStopSignal
+	}
+	if conf.Run.StopTimeout <= 0 {
+		conf.Run.StopTimeout = defaultStopTimeout
+	}
+	if conf.Run.PortTimeout <= 0 {
+		conf.Run.PortTimeout = defaultPortTimeout
+	}
+	if conf.Proxy.Timeout <= 0 {
+		conf.Proxy.Timeout = defaultProxyTimeout
+	}
+
+	// Rust profile and check stage
+	if conf.Rust.Profile == "" {
+		conf.Rust.Profile = defaultRustProfile
+	}
+	if conf.Rust.Check != "" && conf.Rust.Check != "check" && conf.Rust.Check != "clippy" {
+		logger.Log.Warnf("Unknown rust.check '%s', expected check or clippy, disabling it", conf.Rust.Check)
+		conf.Rust.Check = ""
+	}
+
+	applyFlagOverrides()
+}
+
+// applyFlagOverrides applies command line flags on top of the configuration,
+// so they survive configuration hot reloads
+func applyFlagOverrides() {
+	if v, ok := flagOverrides["build-cmd"]; ok {
+		conf.Build.Cmd = v
+	}
+	if v, ok := flagOverrides["package"]; ok {
+		conf.Build.Package = v
+	}
+	if v, ok := flagOverrides["output"]; ok {
+		conf.Build.Output = v
+	}
+	if v, ok := flagOverrides["bin"]; ok {
+		conf.Run.Bin = v
+	}
+	if v, ok := flagOverrides["ldflags"]; ok {
+		conf.Build.Ldflags = v
+	}
+	if v, ok := flagOverrides["gcflags"]; ok {
+		conf.Build.Gcflags = v
+	}
+	if v, ok := flagOverrides["tags"]; ok {
+		conf.Build.Tags = strings.Split(v, ",")
+	}
+	if _, ok := flagOverrides["race"]; ok {
+		conf.Build.Race = true
+	}
+	if _, ok := flagOverrides["trimpath"]; ok {
+		conf.Build.Trimpath = true
+	}
+
+	if len(envOverrides) > 0 && conf.Build.Env == nil {
+		conf.Build.Env = make(map[string]string)
+	}
+	for _, kv := range envOverrides {
+		if i := strings.Index(kv, "="); i > 0 {
+			conf.Build.Env[kv[:i]] = kv[i+1:]
+		}
+	}
 }
 
 // onConfigReload handles configuration hot reload callback
@@ -186,15 +340,17 @@ func onConfigReload(newConfigData interface{}) error {
 
 	// Update configuration atomically
 	runMutex.Lock()
-	oldFreq := conf.Frequency
+	oldDebounce := conf.Debounce
 	oldLang := conf.Lang
 	conf = newConf
 	validateConfig()
 	runMutex.Unlock()
 
+	initMatcher()
+
 	// Log significant changes
-	if oldFreq != conf.Frequency {
-		logger.Log.Infof("Frequency changed from %d to %d seconds", oldFreq, conf.Frequency)
+	if oldDebounce != conf.Debounce {
+		logger.Log.Infof("Debounce changed from %dms to %dms", oldDebounce, conf.Debounce)
 	}
 	if oldLang != conf.Lang {
 		logger.Log.Infof("Language changed from %s to %s", oldLang, conf.Lang)
@@ -237,19 +393,23 @@ func reloadConfig() {
 
 	// Update configuration
 	runMutex.Lock()
-	oldFreq := conf.Frequency
+	oldDebounce := conf.Debounce
 	conf = newConf
 	validateConfig()
 	runMutex.Unlock()
 
+	initMatcher()
+
 	// logger.Log.Success("Configuration reloaded successfully")
 	// Log significant changes
-	if oldFreq != conf.Frequency {
-		logger.Log.Infof("Frequency changed from %d to %d seconds", oldFreq, conf.Frequency)
+	if oldDebounce != conf.Debounce {
+		logger.Log.Infof("Debounce changed from %dms to %dms", oldDebounce, conf.Debounce)
 	}
 }
 
-// Kill kills the running command process with enhanced handling for server processes
+// Kill stops the running app: it sends run.stop_signal to the app (its whole
+// process group with run.kill_group), force kills it after run.stop_timeout
+// and finally kills whatever is left of the group, whatever run.kill_group
 func Kill() {
 	runMutex.Lock()
 	defer runMutex.Unlock()
@@ -260,86 +420,135 @@ func Kill() {
 		}
 	}()
 
+	// The exit is expected, keep the restart policy out of it. Starts still
+	// waiting for the port are stale as well.
+	appGeneration++
+
 	if cmd == nil || cmd.Process == nil {
 		return
 	}
 
 	pid := cmd.Process.Pid
-	logger.Log.Infof("Terminating process (PID: %d)...", pid)
+	group := killGroup()
+	done := appDone
+	pauseProxy()
 
-	// For server processes, try SIGTERM first (more graceful for HTTP servers)
-	var sig os.Signal = syscall.SIGTERM
-	if runtime.GOOS == "windows" {
-		sig = os.Kill // Windows doesn't support SIGTERM properly
+	sig, err := parseSignal(conf.Run.StopSignal)
+	if err != nil {
+		logger.Log.Warnf("Invalid run.stop_signal: %s, using SIGTERM", err)
+		sig = syscall.SIGTERM
 	}
+	name := signalName(conf.Run.StopSignal)
+	timeout := time.Duration(conf.Run.StopTimeout) * time.Millisecond
 
-	if err := cmd.Process.Signal(sig); err != nil {
-		logger.Log.Warnf("Failed to send SIGTERM to process: %s", err)
-		// If SIGTERM fails, try SIGINT
-		if err := cmd.Process.Signal(os.Interrupt); err != nil {
-			logger.Log.Warnf("Failed to send SIGINT to process: %s", err)
-		}
+	logger.Log.Infof("Stopping process (PID: %d) with %s...", pid, name)
+	if err := signalProcess(pid, sig, group); err != nil {
+		logger.Log.Warnf("Failed to send %s: %s", name, err)
 	}
 
-	// Wait for graceful shutdown with shorter timeout for servers
-	done := make(chan error, 1)
-	go func() {
-		done <- cmd.Wait()
-	}()
-
 	select {
-	case err := <-done:
-		if err != nil {
-			logger.Log.Infof("Process terminated with error: %s", err)
-		} else {
-			logger.Log.Info("Process terminated gracefully")
-		}
-	case <-time.After(3 * time.Second): // Shorter timeout for servers
-		logger.Log.Warn("Graceful shutdown timeout, force killing...")
-
-		// Try SIGKILL for force termination
-		if err := cmd.Process.Signal(syscall.SIGKILL); err != nil {
-			// If SIGKILL fails, use Process.Kill()
-			if err := cmd.Process.Kill(); err != nil {
-				logger.Log.Errorf("Failed to force kill process: %s", err)
-			} else {
-				logger.Log.Info("Process force killed with Kill()")
-			}
-		} else {
-			logger.Log.Info("Process force killed with SIGKILL")
+	case <-done:
+		logger.Log.Infof("Process stopped by %s", name)
+	case <-time.After(timeout):
+		logger.Log.Warnf("Process still running after %s, sending SIGKILL...", timeout)
+		if err := signalProcess(pid, syscall.SIGKILL, group); err != nil {
+			logger.Log.Errorf("Failed to force kill process: %s", err)
 		}
 
-		// Wait a bit more for the force kill to complete
 		select {
 		case <-done:
-			logger.Log.Info("Process cleanup completed")
+			logger.Log.Info("Process stopped by SIGKILL")
 		case <-time.After(2 * time.Second):
-			logger.Log.Error("Process may still be running after force kill")
-			// Try to kill process group as last resort
-			if runtime.GOOS != "windows" {
-				logger.Log.Warn("Attempting to kill process group...")
-				// Kill process group (Unix-like systems only)
-				if err := killProcessGroup(pid); err != nil {
-					logger.Log.Errorf("Failed to kill process group: %s", err)
-				}
-			}
+			logger.Log.Error("Process may still be running after SIGKILL")
+		}
+	}
+
+	// Children that outlived the app, e.g. ignoring the stop signal or
+	// never sent it because run.kill_group is off
+	if groupAlive(pid) {
+		if err := killProcessGroup(pid); err != nil {
+			logger.Log.Warnf("Failed to kill leftover processes: %s", err)
+		} else {
+			logger.Log.Infof("Killed leftover processes of group %d", pid)
 		}
 	}
 
 	cmd = nil
 }
 
+// killGroup reports whether the stop signal goes to the whole process group
+// of the app rather than to the app alone
+func killGroup() bool {
+	return conf.Run.KillGroup == nil || *conf.Run.KillGroup
+}
+
+// initMatcher (re)builds the include/exclude matchers of every watch root
+// from the current configuration
+func initMatcher() {
+	matchers := make(map[string]*matcher.Matcher)
+	for _, root := range getWatchRoots() {
+		matchers[root] = matcher.New(root, conf.Include, conf.Exclude, conf.Gitignore)
+	}
+
+	matcherMutex.Lock()
+	fileMatchers = matchers
+	matcherMutex.Unlock()
+}
+
+// getMatcher returns the matcher of the watch root containing name
+func getMatcher(name string) *matcher.Matcher {
+	matcherMutex.RLock()
+	defer matcherMutex.RUnlock()
+
+	var (
+		found *matcher.Matcher
+		best  int
+	)
+	for root, m := range fileMatchers {
+		if (name == root || strings.HasPrefix(name, root+"/")) && len(root) > best {
+			found = m
+			best = len(root)
+		}
+	}
+	return found
+}
+
+// isFilterDir reports whether a directory is skipped by dirfilter or the exclude rules
+func isFilterDir(dir string) bool {
+	if tools.InArray(filepath.Base(dir), conf.DirFilter) {
+		return true
+	}
+	if m := getMatcher(dir); m != nil && m.SkipDir(dir) {
+		return true
+	}
+	return false
+}
+
 func isFilterFile(name string) bool {
+	if isDiagnosticsFile(name) {
+		return true
+	}
+
+	m := getMatcher(name)
+	if m != nil && m.HasInclude() {
+		return !m.MatchFile(name)
+	}
+
 	suffix := path.Ext(name)
 	suffix = strings.Trim(suffix, ".")
 
-	if tools.InArray(suffix, conf.Ext) {
-		return false
+	if !tools.InArray(suffix, conf.Ext) {
+		return true
 	}
-	return true
+	if m != nil && m.Excluded(name, false) {
+		return true
+	}
+	return false
 }
 
-// hasFileChanged checks if a file has actually changed using optimized cached file info
+// hasFileChanged checks if a file has actually changed using cached file info.
+// With content hashing enabled a file whose mtime or size changed is only
+// reported when its bytes differ from the last seen version.
 func hasFileChanged(filename string) bool {
 	fi, err := os.Stat(filename)
 	if err != nil {
@@ -350,37 +559,33 @@ func hasFileChanged(filename string) bool {
 	cachedEntry, exists := fileCache[filename]
 	cacheMutex.RUnlock()
 
-	// Check cache validity (cache expires after 500ms for better performance)
-	if exists && time.Since(cachedEntry.cachedAt) < 500*time.Millisecond {
-		// Use cached result if very recent
-		return fi.ModTime() != cachedEntry.modTime || fi.Size() != cachedEntry.size
+	// Unchanged modification time and size, nothing to do
+	if exists && fi.ModTime() == cachedEntry.modTime && fi.Size() == cachedEntry.size {
+		return false
 	}
 
-	if !exists {
-		// First time seeing this file
-		cacheMutex.Lock()
-		fileCache[filename] = fileCacheEntry{
-			modTime:  fi.ModTime(),
-			size:     fi.Size(),
-			cachedAt: time.Now(),
-		}
-		cacheMutex.Unlock()
-		return true
+	entry := fileCacheEntry{
+		modTime:  fi.ModTime(),
+		size:     fi.Size(),
+		cachedAt: time.Now(),
 	}
 
-	// Check if modification time or size changed
-	changed := fi.ModTime() != cachedEntry.modTime || fi.Size() != cachedEntry.size
-
-	if changed {
-		cacheMutex.Lock()
-		fileCache[filename] = fileCacheEntry{
-			modTime:  fi.ModTime(),
-			size:     fi.Size(),
-			cachedAt: time.Now(),
+	// First time seeing this file or stat info changed
+	changed := true
+	if conf.Hash {
+		if sum, err := tools.FileHash(filename); err == nil {
+			entry.hash = sum
+			entry.hashed = true
+			if exists && cachedEntry.hashed && cachedEntry.hash == sum {
+				changed = false
+			}
 		}
-		cacheMutex.Unlock()
 	}
 
+	cacheMutex.Lock()
+	fileCache[filename] = entry
+	cacheMutex.Unlock()
+
 	return changed
 }
 
@@ -490,18 +695,70 @@ func execCmd(shell string, raw []string) (int, error) {
 	return 0, nil
 }
 
-func CmdAutoBuild(rootPath string) {
-	var (
-		err    error
-		stderr bytes.Buffer
-	)
+func CmdAutoBuild(ctx context.Context, rootPath string) bool {
+	return runBuild(ctx, rootPath, "Go", func() bool {
+		return buildGo(ctx, rootPath)
+	})
+}
 
-	runMutex.Lock()
-	if isBuilding {
-		runMutex.Unlock()
-		logger.Log.Info("Build already in progress, skipping...")
-		return
+// buildGo runs go build, or build.cmd, and replaces the binary once the
+// build succeeded
+func buildGo(ctx context.Context, rootPath string) bool {
+	var stderr bytes.Buffer
+
+	// Change to project directory
+	if err := os.Chdir(rootPath); err != nil {
+		logger.Log.Errorf("Failed to change directory to %s: %s", rootPath, err)
+		return false
 	}
+
+	// Execute build command, go build writes to a temp name first so the
+	// binary is only replaced by a complete build
+	var buildCmd *exec.Cmd
+	output := goOutput(rootPath)
+	if conf.Build.Cmd != "" {
+		logger.Log.Infof("Build command: %s", conf.Build.Cmd)
+		buildCmd = shellCommand(conf.Build.Cmd)
+	} else {
+		if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+			logger.Log.Errorf("Failed to create output directory: %s", err)
+			return false
+		}
+		buildCmd = exec.Command("go", goBuildArgs(rootPath)...)
+	}
+	buildCmd.Dir = rootPath
+	buildCmd.Env = buildEnv("GOGC=off")
+	buildCmd.Stdout = os.Stdout
+	buildCmd.Stderr = &stderr
+
+	err := runCancelable(ctx, buildCmd)
+	if ctx.Err() != nil {
+		logger.Log.Warn("Go build cancelled")
+		return false
+	}
+
+	diags := diag.ParseGo(stderr.String())
+	diag.SetLast(diags)
+	if err != nil {
+		reportBuildFailure("Build failed", stderr.String(), diags)
+		return false
+	}
+
+	if conf.Build.Cmd == "" {
+		if err := replaceBinary(tempOutput(output), output); err != nil {
+			logger.Log.Errorf("Failed to replace binary %s: %s", output, err)
+			return false
+		}
+	}
+
+	logger.Log.Success("Go build completed successfully")
+	return true
+}
+
+// runBuild marks a build in progress and runs the build steps, then build
+// under the build monitor. name is the language shown in the logs.
+func runBuild(ctx context.Context, rootPath, name string, build func() bool) bool {
+	runMutex.Lock()
 	isBuilding = true
 	runMutex.Unlock()
 
@@ -511,283 +768,417 @@ func CmdAutoBuild(rootPath string) {
 		runMutex.Unlock()
 	}()
 
-	//for install
-	install_cmd := exec.Command("go", "install", "-v")
-	install_cmd.Stdout = os.Stdout
-	install_cmd.Stderr = os.Stderr
-	install_cmd.Env = append(os.Environ(), "GOGC=off")
-	err = install_cmd.Run()
-	if err != nil {
-		logger.Log.Errorf("Intall failed: %s", stderr.String())
-		return
+	// Run the configured pre-build steps
+	if !runBuildSteps(ctx, rootPath) {
+		return false
 	}
 
 	// Start performance monitoring
 	stats := monitor.StartBuild()
 	defer stats.EndBuild()
 
-	logger.Log.Info("Starting Go build process...")
+	logger.Log.Infof("Starting %s build process...", name)
 	logger.Log.Infof("System info: %s", monitor.GetSystemInfo())
 
-	// Change to project directory
-	if err := os.Chdir(rootPath); err != nil {
-		logger.Log.Errorf("Failed to change directory to %s: %s", rootPath, err)
+	return build()
+}
+
+// runBuildSteps runs the build.steps commands in order before the build. It
+// stops at the first failing or cancelled step, reports its error output and
+// returns false.
+func runBuildSteps(ctx context.Context, rootPath string) bool {
+	steps := conf.Build.Steps
+	for i, step := range steps {
+		if strings.TrimSpace(step) == "" {
+			continue
+		}
+
+		logger.Log.Infof("Running build step %d/%d: %s", i+1, len(steps), step)
+
+		var stderr bytes.Buffer
+		stepCmd := shellCommand(step)
+		stepCmd.Dir = rootPath
+		stepCmd.Env = buildEnv()
+		stepCmd.Stdout = os.Stdout
+		stepCmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+
+		if err := runCancelable(ctx, stepCmd); err != nil {
+			if ctx.Err() != nil {
+				logger.Log.Warnf("Build step %d/%d cancelled", i+1, len(steps))
+				return false
+			}
+			output := strings.TrimSpace(stderr.String())
+			if output == "" {
+				output = err.Error()
+			}
+
+			// Steps such as "go vet" report source positions
+			diags := currentDriver(rootPath).ParseDiagnostics(output)
+			if len(diags) > 0 {
+				diag.SetLast(diags)
+			}
+			reportBuildFailure(fmt.Sprintf("Build step %d/%d failed (%s)", i+1, len(steps), err), output, diags)
+			return false
+		}
+	}
+	return true
+}
+
+// reportBuildFailure logs a failed build, as a summary grouped by file when
+// diagnostics were found, or the raw error output otherwise
+func reportBuildFailure(title, output string, diags []diag.Diagnostic) {
+	runMutex.Lock()
+	buildFailure = strings.TrimSpace(title + ": " + strings.TrimSpace(output))
+	runMutex.Unlock()
+
+	if len(diags) == 0 {
+		logger.Log.Errorf("%s: %s", title, output)
 		return
 	}
+	logger.Log.Errorf("%s:\n%s", title, strings.TrimRight(diag.Summary(diags), "\n"))
+}
 
-	rootPath = filepath.ToSlash(rootPath)
-	appName := path.Base(rootPath)
+// goOutput returns the path the Go binary is built to, build.output or a
+// binary named after the project directory in the zzz artifact directory
+func goOutput(rootPath string) string {
+	if conf.Build.Output != "" {
+		return conf.Build.Output
+	}
+
+	appName := path.Base(filepath.ToSlash(rootPath))
 	if runtime.GOOS == "windows" {
 		appName += ".exe"
 	}
+	return filepath.Join(artifactDir(rootPath), appName)
+}
 
-	// Build arguments
-	args := []string{"build", "-o", appName}
-	buildLDFlags = strings.TrimSpace(buildLDFlags)
-	if buildLDFlags != "" {
-		args = append(args, "-ldflags", buildLDFlags)
+// goBinary returns the executable started after a Go build, run.bin or the
+// build output by default
+func goBinary(rootPath string) string {
+	bin := conf.Run.Bin
+	if bin == "" {
+		bin = goOutput(rootPath)
 	}
+	return localPath(bin)
+}
 
-	// Execute build command
-	buildCmd := exec.Command("go", args...)
-	buildCmd.Env = append(os.Environ(), "GOGC=off")
-	buildCmd.Stdout = os.Stdout
-	buildCmd.Stderr = &stderr
+// localPath makes a relative executable path explicit so it is not looked up in $PATH
+func localPath(bin string) string {
+	if filepath.IsAbs(bin) || strings.HasPrefix(bin, "./") || strings.HasPrefix(bin, "../") {
+		return bin
+	}
+	return "./" + bin
+}
 
-	err = buildCmd.Run()
-	if err != nil {
-		logger.Log.Errorf("Build failed: %s", stderr.String())
-		return
+// shellCommand returns a command running script through the system shell
+func shellCommand(script string) *exec.Cmd {
+	name, args := shellArgs(script)
+	return exec.Command(name, args...)
+}
+
+// shellArgs returns the shell and arguments running script
+func shellArgs(script string) (string, []string) {
+	if runtime.GOOS == "windows" {
+		return "cmd", []string{"/C", script}
 	}
+	return "sh", []string{"-c", script}
+}
 
-	logger.Log.Success("Go build completed successfully")
+// shellScriptArgs returns the shell and arguments running script, arguments
+// appended to them become the positional parameters of script, which uses
+// them as "$@". The script itself is passed unchanged, so it may span lines
+// and end in a comment.
+func shellScriptArgs(script string) (string, []string) {
+	if runtime.GOOS == "windows" {
+		return shellArgs(script)
+	}
+	return "sh", []string{"-c", script, "zzz"}
+}
 
-	CmdRestart(rootPath)
+// binExecutable returns run.bin for drivers that cannot tell which program
+// their build produces
+func binExecutable(rootPath string) (string, []string, error) {
+	if conf.Run.Bin == "" {
+		return "", nil, fmt.Errorf("set run.bin to the program to run")
+	}
+	return localPath(conf.Run.Bin), nil, nil
 }
 
 func CmdRestart(rootPath string) {
 	Kill()
-	go CmdStart(rootPath)
+	resetRestarts()
+
+	// Taken now, a Kill running before the goroutine makes this start stale
+	runMutex.RLock()
+	gen := appGeneration
+	runMutex.RUnlock()
+
+	go CmdStart(rootPath, gen)
 }
 
-func CmdStart(rootPath string) {
+// CmdStart starts the app once run.port is free. gen is the app generation
+// the start was requested at, see startApp.
+func CmdStart(rootPath string, gen uint64) {
+	runMutex.RLock()
+	quit := exiting
+	runMutex.RUnlock()
+	if quit {
+		return
+	}
+
+	if !waitPort() {
+		logger.Log.Error("Not starting the application, free the port and save a file to retry")
+		return
+	}
+
 	runMutex.Lock()
 	defer runMutex.Unlock()
 
-	if err := os.Chdir(rootPath); err != nil {
-		logger.Log.Errorf("Failed to change directory to %s: %s", rootPath, err)
+	startApp(rootPath, gen)
+}
+
+// startApp starts the app, the caller holds runMutex. gen is the app
+// generation seen before waiting for the port; nothing is started when an
+// instance is running or the app was started or stopped meanwhile, by another
+// restart that took over. Nothing is started once zzz is exiting.
+func startApp(rootPath string, gen uint64) {
+	if exiting || gen != appGeneration || cmd != nil {
 		return
 	}
 
-	appName := path.Base(rootPath)
-	if runtime.GOOS == "windows" {
-		appName += ".exe"
+	if err := os.Chdir(rootPath); err != nil {
+		logger.Log.Errorf("Failed to change directory to %s: %s", rootPath, err)
+		return
 	}
 
-	logger.Log.Infof("Starting '%s'...", appName)
-
-	// Ensure executable path is correct
-	if !strings.Contains(appName, "./") {
-		appName = "./" + appName
+	appName, args, err := currentDriver(rootPath).Executable(rootPath)
+	if err != nil {
+		logger.Log.Errorf("Failed to locate executable: %s", err)
+		return
 	}
-
-	// Check if executable exists
-	if !tools.IsFile(appName) {
+	env := runEnv(rootPath)
+	args = append(args, runArgs(env)...)
+	logger.Log.Infof("Starting '%s'...", strings.Join(append([]string{appName}, args...), " "))
+
+	// Check if executable exists, bare names are looked up in $PATH
+	if strings.ContainsAny(appName, `/\`) {
+		if !tools.IsFile(appName) {
+			logger.Log.Errorf("Executable not found: %s", appName)
+			return
+		}
+	} else if _, err := exec.LookPath(appName); err != nil {
 		logger.Log.Errorf("Executable not found: %s", appName)
 		return
 	}
 
-	cmd = exec.Command(appName)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	files := socketFiles()
+	name, args, env := withSockets(appName, args, env, files)
+
+	// A config reload while the app starts must not change its probes
+	probes := conf.Run.Ready
+	logs := newLogWaiters(probes)
+	cmd = exec.Command(name, args...)
+	cmd.Env = env
+	cmd.ExtraFiles = files
+	cmd.Stdout = logs.tap(os.Stdout)
+	cmd.Stderr = logs.tap(os.Stderr)
 
 	// Set process group for better process management (Unix-like systems)
 	cmd.SysProcAttr = setProcAttributes()
 
-	// Start the process in a goroutine
-	go func() {
-		if err := cmd.Run(); err != nil {
-			logger.Log.Errorf("Application exited with error: %s", err)
-		} else {
-			logger.Log.Info("Application exited normally")
-		}
-	}()
-
-	// Give the process a moment to start
-	time.Sleep(100 * time.Millisecond)
-
-	logger.Log.Successf("'%s' is running...", appName)
-
-	// Non-blocking send to started channel
-	select {
-	case started <- true:
-	default:
+	if err := cmd.Start(); err != nil {
+		logger.Log.Errorf("Failed to start '%s': %s", appName, err)
+		cmd = nil
+		return
 	}
+
+	startedAt := time.Now()
+	appGeneration++
+	appDone = make(chan struct{})
+	go waitApp(rootPath, cmd, appGeneration, appDone, startedAt)
+	go awaitReady(rootPath, appName, appGeneration, appDone, startedAt, probes, logs)
 }
 
 func CmdDone(rootPath string) {
-	// runMutex.Lock()
-	// defer runMutex.Unlock()
+	ctx, cancel := newBuildContext()
+	defer cancel()
 
 	CmdRunBefore(rootPath)
-	// time.Sleep(1 * time.Second)
 
+	built := false
 	if conf.EnableRun {
+		diag.SetLast(nil)
+		runMutex.Lock()
+		buildFailure = ""
+		runMutex.Unlock()
 
-		if tools.IsRustP() {
-			CmdAutoBuildRust(rootPath)
-		} else if tools.IsGoP() {
-			CmdAutoBuild(rootPath)
-		} else {
-			logger.Log.Info("Invalid language environment")
+		// A build finishing as zzz exits must not start the app again
+		built = currentDriver(rootPath).Build(ctx, rootPath)
+		if built && ctx.Err() == nil {
+			CmdRestart(rootPath)
 		}
+	}
 
+	// A newer build is queued, it runs the after hooks
+	if ctx.Err() != nil {
+		return
+	}
+
+	if conf.EnableRun {
+		writeDiagnostics(rootPath, built)
 	}
-	// time.Sleep(1 * time.Second)
 	CmdRunAfter(rootPath)
+}
+
+// writeDiagnostics writes the diagnostics of the latest build to the
+// diagnostics file, an empty list after a clean build. A failed build without
+// any error pointing at a source file, e.g. a link error or a missing
+// toolchain, is written as an error holding the build output.
+func writeDiagnostics(rootPath string, built bool) {
+	file := diagnosticsFile(rootPath)
+	if file == "" {
+		return
+	}
 
+	diags := diag.Last()
+	if errs, _ := diag.Count(diags); !built && errs == 0 {
+		runMutex.RLock()
+		message := buildFailure
+		runMutex.RUnlock()
+		if message == "" {
+			message = "Build failed, see the zzz output"
+		}
+		diags = append(diags, diag.Diagnostic{Severity: diag.SeverityError, Message: message})
+	}
+
+	if err := diag.WriteFile(file, conf.Diagnostics.Format, diags); err != nil {
+		logger.Log.Warnf("Failed to write diagnostics file: %s", err)
+	}
 }
 
-func initWatcher(rootPath string) {
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		logger.Log.Fatalf("Failed to create watcher: %s", err)
+// diagnosticsFile returns the absolute path of diagnostics.file, if set
+func diagnosticsFile(rootPath string) string {
+	file := conf.Diagnostics.File
+	if file == "" {
+		return ""
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(rootPath, file)
+	}
+	return filepath.Clean(file)
+}
+
+// isDiagnosticsFile reports whether name is the diagnostics file zzz writes,
+// or its temporary file; watching it would rebuild after every build
+func isDiagnosticsFile(name string) bool {
+	roots := getWatchRoots()
+	if len(roots) == 0 {
+		return false
+	}
+	file := diagnosticsFile(roots[0])
+	if file == "" {
+		return false
 	}
 
+	name = filepath.Clean(filepath.FromSlash(name))
+	return name == file || name == diag.TempFile(file)
+}
+
+func initWatcher(rootPath string) {
 	logger.Log.Info("Initializing file watcher...")
 
-	// Channel for debounced file changes
-	fileChanges := make(chan string, 100)
-	buildTrigger := make(chan bool, 1)
+	// Changed files waiting for the debouncer
+	changes := newChangeSet()
 
 	// File event processor with debouncing
-	go func() {
-		changedFiles := make(map[string]time.Time)
-		ticker := time.NewTicker(time.Duration(conf.Frequency) * time.Second)
-		defer ticker.Stop()
-
-		// Config reload ticker (check every 5 seconds)
-		configTicker := time.NewTicker(5 * time.Second)
-		defer configTicker.Stop()
-
-		for {
-			select {
-			case filename := <-fileChanges:
-				changedFiles[filename] = time.Now()
-
-			case <-ticker.C:
-				if len(changedFiles) > 0 {
-					// Clear the map and trigger build
-					fileCount := len(changedFiles)
-					changedFiles = make(map[string]time.Time)
-
-					logger.Log.Infof("Detected changes in %d file(s), triggering rebuild...", fileCount)
-
-					// Non-blocking send to build trigger
-					select {
-					case buildTrigger <- true:
-					default:
-						// Build already queued
-					}
-				}
-
-				// Update ticker frequency if config changed
-				runMutex.RLock()
-				currentFreq := conf.Frequency
-				runMutex.RUnlock()
-
-				if ticker.C != nil {
-					ticker.Stop()
-					ticker = time.NewTicker(time.Duration(currentFreq) * time.Second)
-				}
-
-			case <-configTicker.C:
-				// Check for config file changes
-				reloadConfig()
-			}
-		}
-	}()
+	go debounceChanges(changes)
 
 	// Build processor
-	go func() {
-		for range buildTrigger {
-			CmdDone(rootPath)
-		}
-	}()
+	go buildLoop(rootPath)
+	go watchEnvFile(rootPath)
 
-	// File system event processor
-	go func() {
-		for {
-			select {
-			case event := <-watcher.Events:
-				// Skip filtered files
-				if isFilterFile(event.Name) {
-					continue
-				}
-
-				// Only process write and create events
-				if event.Op&fsnotify.Write == fsnotify.Write || event.Op&fsnotify.Create == fsnotify.Create {
-					// Use improved file change detection
-					if hasFileChanged(event.Name) {
-						logger.Log.Hintf(colors.Bold("Changed: ")+"%s", event.Name)
-
-						// Send to debouncer
-						select {
-						case fileChanges <- event.Name:
-						default:
-							// Channel full, skip this event
-							logger.Log.Hintf(colors.Bold("Queue full, skipping: ")+"%s", event.Name)
-						}
-					} else {
-						logger.Log.Hintf(colors.Bold("Skipping: ")+"%s (no change)", event.Name)
-					}
-				}
-
-			case err := <-watcher.Errors:
-				logger.Log.Warnf("Watcher error: %s", err)
-			}
-		}
-	}()
+	initWatchRoots(rootPath)
+	initMatcher()
 
-	// Add directories to watcher
-	dirs := tools.GetPathDir(rootPath, conf.DirFilter)
-	dirs = tools.GetVailDir(dirs, conf.Ext)
+	// Record every watched file up front, so removals are noticed and, with
+	// content hashing, touching a file never seen before is not a change
+	seedFileCache()
 
-	for _, dir := range dirs {
-		if err := watcher.Add(dir); err != nil {
-			logger.Log.Warnf("Failed to watch directory %s: %s", dir, err)
-			logger.Log.Info("Tip: If you see 'too many open files', try: ulimit -n 2048")
-		} else {
-			logger.Log.Hintf(colors.Bold("Watching: ")+"%s", dir)
+	mode := conf.Watcher
+	if mode == WatcherPoll {
+		startPolling(changes)
+		return
+	}
+
+	if err := startNotify(changes, mode == WatcherAuto); err != nil {
+		if mode != WatcherAuto {
+			logger.Log.Fatalf("Failed to create watcher: %s", err)
 		}
+		logger.Log.Warnf("File system notifications unavailable (%s), falling back to polling", err)
+		startPolling(changes)
 	}
+}
 
-	logger.Log.Successf("File watcher initialized, monitoring %d directories", len(dirs))
+// notifyChange forwards a file to the debouncer when its content changed
+func notifyChange(name string, changes *changeSet) {
+	// Use improved file change detection
+	if !hasFileChanged(name) {
+		logger.Log.Hintf(colors.Bold("Skipping: ")+"%s (no change)", name)
+		return
+	}
+
+	queueChange("Changed", name, changes)
+}
+
+// queueChange records a changed or removed file for the debouncer
+func queueChange(kind, name string, changes *changeSet) {
+	logger.Log.Hintf(colors.Bold(kind+": ")+"%s", name)
+	changes.Add(name)
 }
 
 func CmdRun(c *cli.Context) error {
 	ShowShortVersionBanner()
 
-	buildLDFlags = c.String("ldflags")
+	for _, name := range []string{"build-cmd", "package", "output", "bin", "ldflags", "gcflags", "tags"} {
+		if v := c.String(name); v != "" {
+			flagOverrides[name] = v
+		}
+	}
+	for _, name := range []string{"race", "trimpath"} {
+		if c.Bool(name) {
+			flagOverrides[name] = "true"
+		}
+	}
+	envOverrides = c.StringSlice("env")
+	passArgs = c.Args()
+	applyFlagOverrides()
 
 	rootPath, _ := os.Getwd()
 	appName := path.Base(rootPath)
 	logger.Log.Infof("Using '%s' as 'appname'", appName)
 
+	startProxy()
 	initWatcher(rootPath)
-	CmdDone(rootPath)
-
-	for {
-		chanel := make(chan os.Signal)
-		signal.Notify(chanel, syscall.SIGINT)
-		sig := <-chanel
-
-		if sig == syscall.SIGINT {
-			fmt.Println("\n")
-			logger.Log.Info(fmt.Sprintf("exit: %s", appName))
-			CmdRunExit(rootPath)
-			break
-		}
-	}
+	requestBuild()
+
+	chanel := make(chan os.Signal, 1)
+	signal.Notify(chanel, syscall.SIGINT, syscall.SIGTERM)
+	<-chanel
+
+	fmt.Println()
+	logger.Log.Info(fmt.Sprintf("exit: %s", appName))
+
+	// Pending restarts must not start an app nothing would stop anymore
+	runMutex.Lock()
+	exiting = true
+	runMutex.Unlock()
+
+	// Build steps run in their own process group and miss the terminal's
+	// SIGINT, kill them before the artifacts are removed
+	stopBuilds()
+	Kill()
+	closeSockets()
+	CmdRunExit(rootPath)
+	removeArtifacts(rootPath)
 	return nil
 }
@@ -1,117 +1,238 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"os"
 	"os/exec"
-	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
-	"time"
+	"sync"
 
+	"github.com/midoks/zzz/internal/cargo"
+	"github.com/midoks/zzz/internal/diag"
 	"github.com/midoks/zzz/internal/logger"
-	"github.com/midoks/zzz/internal/monitor"
 )
 
-func CmdAutoBuildRust(rootPath string) {
-	runMutex.Lock()
-	if isBuilding {
-		runMutex.Unlock()
-		logger.Log.Info("Rust build already in progress, skipping...")
-		return
-	}
-	isBuilding = true
-	runMutex.Unlock()
+// defaultRustProfile keeps release builds unless rust.profile says otherwise
+const defaultRustProfile = "release"
 
-	defer func() {
-		runMutex.Lock()
-		isBuilding = false
-		runMutex.Unlock()
-	}()
+// ansiEscape matches the color codes of the rendered cargo messages
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*[A-Za-z]")
 
-	// Start performance monitoring
-	stats := monitor.StartBuild()
-	defer stats.EndBuild()
-
-	logger.Log.Info("Starting Rust build process...")
-	logger.Log.Infof("System info: %s", monitor.GetSystemInfo())
+func CmdAutoBuildRust(ctx context.Context, rootPath string) bool {
+	return runBuild(ctx, rootPath, "Rust", func() bool {
+		return buildRust(ctx, rootPath)
+	})
+}
 
+// buildRust runs the cargo check and build, and verifies the binary to run
+// was built
+func buildRust(ctx context.Context, rootPath string) bool {
 	// Change to project directory
 	if err := os.Chdir(rootPath); err != nil {
 		logger.Log.Errorf("Failed to change directory to %s: %s", rootPath, err)
-		return
+		return false
 	}
 
-	// Execute cargo build
-	buildCmd := exec.Command("cargo", "build", "--release")
-	buildCmd.Dir = rootPath
-	buildCmd.Env = append(os.Environ())
-	buildCmd.Stdout = os.Stdout
-	buildCmd.Stderr = os.Stderr
+	// Report errors early with cargo check or clippy
+	if conf.Rust.Check != "" && conf.Build.Cmd == "" {
+		if !runCargo(ctx, rootPath, conf.Rust.Check) {
+			return false
+		}
+	}
 
-	if err := buildCmd.Run(); err != nil {
-		logger.Log.Errorf("Rust build failed: %s", err)
-		return
+	// Execute cargo build
+	if !runCargo(ctx, rootPath, "build") {
+		return false
 	}
 
 	// Verify that the executable was created
-	appName := path.Base(rootPath)
-	executablePath := "./target/release/" + appName
+	executablePath, err := rustBinary(rootPath)
+	if err != nil {
+		logger.Log.Errorf("Failed to locate Rust executable: %s", err)
+		return false
+	}
 	if _, err := os.Stat(executablePath); os.IsNotExist(err) {
 		logger.Log.Errorf("Rust executable not found after build: %s", executablePath)
-		logger.Log.Info("This might be due to a mismatch between project name and binary name")
-		return
+		logger.Log.Info("Set run.bin to the binary name or path to run")
+		return false
 	}
 
 	logger.Log.Success("Rust build completed successfully")
+	return true
+}
 
-	Kill()
-	CmdStartRust(rootPath)
+// runCargo runs a cargo subcommand (build, check or clippy) with the
+// configured profile, features and target, or build.cmd for "build". It
+// reports the parsed diagnostics and returns whether it succeeded.
+func runCargo(ctx context.Context, rootPath, subcommand string) bool {
+	var buildCmd *exec.Cmd
+	if subcommand == "build" && conf.Build.Cmd != "" {
+		logger.Log.Infof("Build command: %s", conf.Build.Cmd)
+		buildCmd = shellCommand(conf.Build.Cmd)
+	} else {
+		if subcommand != "build" {
+			logger.Log.Infof("Running cargo %s...", subcommand)
+		}
+		buildCmd = exec.Command("cargo", cargoArgs(subcommand)...)
+	}
+	output := &cargoWriter{}
+	buildCmd.Dir = rootPath
+	buildCmd.Env = buildEnv()
+	buildCmd.Stdout = output
+	buildCmd.Stderr = cargoStderr{output}
+
+	err := runCancelable(ctx, buildCmd)
+	output.Flush()
+	if ctx.Err() != nil {
+		logger.Log.Warnf("Rust %s cancelled", subcommand)
+		return false
+	}
+
+	diag.SetLast(output.diags)
+	if err != nil {
+		reportBuildFailure(fmt.Sprintf("Rust %s failed (%s)", subcommand, err), output.String(), output.diags)
+		return false
+	}
+	if _, warnings := diag.Count(output.diags); warnings > 0 {
+		logger.Log.Warnf("Rust %s finished with %d warning(s)", subcommand, warnings)
+	}
+	return true
 }
 
-func CmdStartRust(rootPath string) {
-	runMutex.Lock()
-	defer runMutex.Unlock()
+// cargoArgs returns the arguments of a cargo subcommand for the rust: and
+// build: configuration
+func cargoArgs(subcommand string) []string {
+	args := []string{subcommand, "--message-format=json-diagnostic-rendered-ansi"}
+	switch conf.Rust.Profile {
+	case "", "dev":
+	case "release":
+		args = append(args, "--release")
+	default:
+		args = append(args, "--profile", conf.Rust.Profile)
+	}
+	if conf.Build.Package != "" {
+		args = append(args, "--package", conf.Build.Package)
+	}
+	if len(conf.Rust.Features) > 0 {
+		args = append(args, "--features", strings.Join(conf.Rust.Features, ","))
+	}
+	if conf.Rust.NoDefaultFeatures {
+		args = append(args, "--no-default-features")
+	}
+	if conf.Rust.Target != "" {
+		args = append(args, "--target", conf.Rust.Target)
+	}
+	return append(args, conf.Build.Flags...)
+}
 
-	if err := os.Chdir(rootPath); err != nil {
-		logger.Log.Errorf("Failed to change directory to %s: %s", rootPath, err)
-		return
+// rustBinaryDir returns the directory cargo writes executables to for the
+// configured profile and target
+func rustBinaryDir(rootPath string) string {
+	dir := cargo.TargetDir(rootPath, buildEnv())
+	if conf.Rust.Target != "" {
+		dir = filepath.Join(dir, conf.Rust.Target)
 	}
+	return filepath.Join(dir, cargo.ProfileDir(conf.Rust.Profile))
+}
 
-	appName := path.Base(rootPath)
-	logger.Log.Infof("Starting '%s'...", appName)
+// rustBinary returns the executable started after a Rust build. run.bin is
+// either a path or the name of a binary target; without it the binary is
+// picked from Cargo.toml (default-run, the only binary, or the one named
+// after the package), among those of build.package when set, inside the
+// cargo target directory.
+func rustBinary(rootPath string) (string, error) {
+	bin := conf.Run.Bin
+	if strings.ContainsAny(bin, `/\`) {
+		return localPath(bin), nil
+	}
 
-	// Ensure executable path is correct
-	if !strings.Contains(appName, "./") {
-		appName = "./target/release/" + appName
+	dir := rustBinaryDir(rootPath)
+	project, err := cargo.Load(rootPath)
+	if err != nil {
+		if bin == "" {
+			bin = filepath.Base(rootPath)
+		}
+		return cargo.Executable(dir, cargo.Bin{Name: bin}), nil
 	}
 
-	// Check if executable exists
-	if _, err := os.Stat(appName); os.IsNotExist(err) {
-		logger.Log.Errorf("Rust executable not found: %s", appName)
-		logger.Log.Info("Make sure 'cargo build --release' completed successfully")
-		return
+	selected, err := project.Select(bin, conf.Build.Package)
+	if err != nil {
+		return "", err
 	}
+	return cargo.Executable(dir, selected), nil
+}
 
-	cmd = exec.Command(appName)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+// cargoWriter receives the stdout of cargo --message-format=json. Compiler
+// messages are printed in their rendered form and collected as diagnostics,
+// anything that is not a cargo message is passed through.
+type cargoWriter struct {
+	pending []byte
+	diags   []diag.Diagnostic
 
-	// Start the process in a goroutine
-	go func() {
-		if err := cmd.Run(); err != nil {
-			logger.Log.Errorf("Rust application exited with error: %s", err)
-		} else {
-			logger.Log.Info("Rust application exited normally")
-		}
-	}()
+	// rendered keeps the messages and the stderr of cargo for failure reports
+	mutex    sync.Mutex
+	rendered bytes.Buffer
+}
 
-	// Give the process a moment to start
-	time.Sleep(100 * time.Millisecond)
+func (w *cargoWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		w.handle(string(w.pending[:i]))
+		w.pending = w.pending[i+1:]
+	}
+	return len(p), nil
+}
 
-	logger.Log.Successf("'%s' is running...", appName)
+// Flush handles a trailing line without newline
+func (w *cargoWriter) Flush() {
+	if len(w.pending) > 0 {
+		w.handle(string(w.pending))
+		w.pending = nil
+	}
+}
 
-	// Non-blocking send to started channel
-	select {
-	case started <- true:
-	default:
+func (w *cargoWriter) handle(line string) {
+	d, rendered, ok := diag.ParseCargoLine(line)
+	if !ok {
+		fmt.Fprintln(os.Stdout, line)
+		return
+	}
+	if rendered != "" {
+		fmt.Fprint(os.Stderr, rendered)
+		w.keep([]byte(rendered))
 	}
+	if d != nil {
+		w.diags = append(w.diags, *d)
+	}
+}
+
+func (w *cargoWriter) keep(p []byte) {
+	w.mutex.Lock()
+	w.rendered.Write(p)
+	w.mutex.Unlock()
+}
+
+// String returns the rendered messages and stderr of cargo without colors
+func (w *cargoWriter) String() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return ansiEscape.ReplaceAllString(w.rendered.String(), "")
+}
+
+// cargoStderr passes the stderr of cargo through and keeps it in cargoWriter
+type cargoStderr struct {
+	w *cargoWriter
+}
+
+func (e cargoStderr) Write(p []byte) (int, error) {
+	e.w.keep(p)
+	return os.Stderr.Write(p)
 }
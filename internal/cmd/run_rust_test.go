@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"io"
+	"testing"
+)
+
+func TestCargoWriterOutput(t *testing.T) {
+	w := &cargoWriter{}
+	io.WriteString(w, `{"reason":"compiler-message","message":{"message":"linking failed","level":"error","rendered":"\u001b[31merror\u001b[0m: linking failed\n","spans":[]}}`+"\n")
+	cargoStderr{w}.Write([]byte("error: could not compile `app`\n"))
+	w.Flush()
+
+	want := "error: linking failed\nerror: could not compile `app`\n"
+	if got := w.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if len(w.diags) != 0 {
+		t.Errorf("diags = %+v, want none", w.diags)
+	}
+}
@@ -3,7 +3,47 @@
 
 package cmd
 
-import "syscall"
+import (
+	"fmt"
+	"strconv"
+	"syscall"
+)
+
+// stopSignals are the signals accepted by run.stop_signal
+var stopSignals = map[string]syscall.Signal{
+	"SIGTERM": syscall.SIGTERM,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
+
+// parseSignal parses a signal name (SIGTERM, TERM, term) or number
+func parseSignal(name string) (syscall.Signal, error) {
+	name = signalName(name)
+	if sig, ok := stopSignals[name]; ok {
+		return sig, nil
+	}
+	if n, err := strconv.Atoi(name); err == nil && n > 0 {
+		return syscall.Signal(n), nil
+	}
+	return 0, fmt.Errorf("unknown signal '%s'", name)
+}
+
+// signalProcess sends sig to the process, or to its whole process group
+func signalProcess(pid int, sig syscall.Signal, group bool) error {
+	if group {
+		return syscall.Kill(-pid, sig)
+	}
+	return syscall.Kill(pid, sig)
+}
+
+// groupAlive reports whether a process of the group still exists
+func groupAlive(pid int) bool {
+	return syscall.Kill(-pid, 0) == nil
+}
 
 // killProcessGroup kills a process group (Unix-like systems only)
 func killProcessGroup(pid int) error {
@@ -3,11 +3,38 @@
 
 package cmd
 
-import "syscall"
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
 
-// killProcessGroup kills a process group (not supported on Windows)
+// parseSignal accepts any signal name, Windows processes can only be killed
+func parseSignal(name string) (syscall.Signal, error) {
+	return syscall.SIGKILL, nil
+}
+
+// signalProcess kills the process, or the process and all of its children
+func signalProcess(pid int, sig syscall.Signal, group bool) error {
+	if group {
+		return killProcessGroup(pid)
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
+
+// groupAlive is false, killProcessGroup already covers the whole tree
+func groupAlive(pid int) bool {
+	return false
+}
+
+// killProcessGroup kills a process and all of its children
 func killProcessGroup(pid int) error {
-	return nil // Not supported on Windows
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(pid)).Run()
 }
 
 // setProcAttributes sets process attributes for better process management
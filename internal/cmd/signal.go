@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"strings"
+)
+
+const (
+	defaultStopSignal  = "SIGTERM"
+	defaultStopTimeout = 3000 // milliseconds
+)
+
+// signalName normalizes a signal name for logs, "term" becomes "SIGTERM"
+func signalName(name string) string {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if name == "" {
+		return defaultStopSignal
+	}
+	if !strings.HasPrefix(name, "SIG") && (name[0] < '0' || name[0] > '9') {
+		name = "SIG" + name
+	}
+	return name
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/midoks/zzz/internal/logger"
+	"github.com/midoks/zzz/internal/tools"
+)
+
+// listenFdsStart is the first file descriptor passed to the app, as with
+// systemd socket activation
+const listenFdsStart = 3
+
+var (
+	// sockets are the open run.listen sockets by address
+	sockets     = make(map[string]*os.File)
+	socketMutex sync.Mutex
+)
+
+// socketFiles returns the run.listen sockets owned by zzz, in configuration
+// order. They are opened on first use and kept open across restarts, so
+// connections queue in the backlog while the app restarts instead of being
+// refused. An address that could not be bound is retried on the next start.
+func socketFiles() []*os.File {
+	socketMutex.Lock()
+	defer socketMutex.Unlock()
+
+	addrs := conf.Run.Listen
+	for addr, f := range sockets {
+		if !tools.InArray(addr, addrs) {
+			f.Close()
+			delete(sockets, addr)
+		}
+	}
+
+	if len(addrs) > 0 && runtime.GOOS == "windows" {
+		logger.Log.Warn("Socket handoff (run.listen) is not supported on Windows")
+		return nil
+	}
+
+	var files []*os.File
+	for _, addr := range addrs {
+		f, ok := sockets[addr]
+		if !ok {
+			var err error
+			if f, err = listenFile(addr); err != nil {
+				logger.Log.Errorf("Failed to listen on %s, retrying on next start: %s", addr, err)
+				continue
+			}
+			logger.Log.Infof("Listening on %s, handed to the app as fd %d", addr, listenFdsStart+len(files))
+			sockets[addr] = f
+		}
+		files = append(files, f)
+	}
+	if len(files) < len(addrs) {
+		logger.Log.Warnf("Handing %d of %d run.listen sockets to the app (LISTEN_FDS=%d)", len(files), len(addrs), len(files))
+	}
+	return files
+}
+
+// listenFile opens a TCP listener and returns its file
+func listenFile(addr string) (*os.File, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	// File returns a duplicate, the socket stays open after Close
+	defer l.Close()
+	return l.(*net.TCPListener).File()
+}
+
+// closeSockets closes the sockets owned by zzz
+func closeSockets() {
+	socketMutex.Lock()
+	closeSocketsLocked()
+	socketMutex.Unlock()
+}
+
+func closeSocketsLocked() {
+	for addr, f := range sockets {
+		f.Close()
+		delete(sockets, addr)
+	}
+}
+
+// ownsPort reports whether zzz holds a run.listen socket on port
+func ownsPort(port int) bool {
+	for _, addr := range conf.Run.Listen {
+		if _, p, err := net.SplitHostPort(addr); err == nil && p == strconv.Itoa(port) {
+			return true
+		}
+	}
+	return false
+}
+
+// ownsAddr reports whether a host:port address is served by a run.listen
+// socket, i.e. zzz holds a socket on its port
+func ownsAddr(addr string) bool {
+	_, p, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	port, err := strconv.Atoi(p)
+	return err == nil && ownsPort(port)
+}
+
+// withSockets hands the sockets to the app command: they become fds 3 and
+// up, LISTEN_FDS tells how many, and LISTEN_PID is set by a shell wrapper
+// to the pid of the app, which the shell execs into.
+func withSockets(name string, args, env []string, files []*os.File) (string, []string, []string) {
+	if len(files) == 0 {
+		return name, args, env
+	}
+
+	names := make([]string, len(files))
+	for i := range files {
+		names[i] = "zzz"
+	}
+	env = append(env,
+		fmt.Sprintf("LISTEN_FDS=%d", len(files)),
+		"LISTEN_FDNAMES="+strings.Join(names, ":"),
+	)
+
+	wrapped := append([]string{"-c", `LISTEN_PID=$$; export LISTEN_PID; exec "$0" "$@"`, name}, args...)
+	return "sh", wrapped, env
+}
@@ -22,7 +22,9 @@ func CmdStatus(c *cli.Context) error {
 	logger.Log.Info("\n=== Configuration ===")
 	logger.Log.Infof("Language: %s", conf.Lang)
 	logger.Log.Infof("Extensions: %v", conf.Ext)
-	logger.Log.Infof("Frequency: %d seconds", conf.Frequency)
+	logger.Log.Infof("Debounce: %dms (max wait: %dms)", conf.Debounce, conf.MaxWait)
+	logger.Log.Infof("Watcher: %s (poll interval: %dms)", conf.Watcher, conf.PollInterval)
+	logger.Log.Infof("Content Hash: %v", conf.Hash)
 
 	logger.Log.Info("\n=== Build Status ===")
 	runMutex.RLock()
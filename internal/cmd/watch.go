@@ -0,0 +1,330 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/midoks/zzz/internal/logger"
+	"github.com/midoks/zzz/internal/logger/colors"
+	"github.com/midoks/zzz/internal/matcher"
+	"github.com/midoks/zzz/internal/tools"
+	"github.com/midoks/zzz/internal/workspace"
+)
+
+// Watcher backends
+const (
+	WatcherAuto   = "auto"
+	WatcherNotify = "fsnotify"
+	WatcherPoll   = "poll"
+)
+
+// atomicSaveDelay is how long a removed or renamed file may take to reappear.
+// Editors saving atomically replace the original within a few milliseconds.
+const atomicSaveDelay = 100 * time.Millisecond
+
+var (
+	watchRoots  []string
+	watchedDirs = make(map[string]bool)
+	watchMutex  sync.RWMutex
+)
+
+// startNotify watches every watch root with fsnotify. With fallback set, running out
+// of inotify watches is reported as an error (and later switches to polling)
+// instead of leaving parts of the tree unwatched.
+func startNotify(changes *changeSet, fallback bool) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	for _, root := range getWatchRoots() {
+		if _, err := watchDir(watcher, root); err != nil && fallback && isWatchLimitError(err) {
+			closeNotify(watcher)
+			return err
+		}
+	}
+
+	go notifyLoop(watcher, changes, fallback)
+
+	logger.Log.Successf("File watcher initialized, monitoring %d directories", watchedDirCount())
+	return nil
+}
+
+// notifyLoop processes fsnotify events until the watcher is closed
+func notifyLoop(watcher *fsnotify.Watcher, changes *changeSet, fallback bool) {
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			// Ignore files changed, re-read them on next match
+			if matcher.IsIgnoreFile(event.Name) {
+				if m := getMatcher(event.Name); m != nil {
+					m.Reset()
+				}
+				continue
+			}
+
+			// Keep the watched tree in sync with directories created or removed
+			if event.Op&fsnotify.Create == fsnotify.Create && tools.IsDir(event.Name) {
+				if isFilterDir(event.Name) {
+					continue
+				}
+				count, err := watchDir(watcher, event.Name)
+				if err != nil && fallback && isWatchLimitError(err) {
+					logger.Log.Warnf("Out of file watches (%s), falling back to polling", err)
+					closeNotify(watcher)
+					startPolling(changes)
+					return
+				}
+				if count > 0 {
+					for _, name := range newDirFiles(event.Name) {
+						notifyChange(name, changes)
+					}
+				}
+				continue
+			}
+			if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 && unwatchDir(watcher, event.Name) {
+				for _, name := range forgetDir(event.Name) {
+					queueChange("Removed", name, changes)
+				}
+				continue
+			}
+
+			// Skip filtered files
+			if isFilterFile(event.Name) {
+				continue
+			}
+
+			if event.Op&fsnotify.Write == fsnotify.Write || event.Op&fsnotify.Create == fsnotify.Create {
+				notifyChange(event.Name, changes)
+			} else if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				notifyRemove(event.Name, changes)
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Log.Warnf("Watcher error: %s", err)
+
+			// Events were lost, compare the whole tree against fileCache
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				resyncNotify(watcher, changes)
+			}
+		}
+	}
+}
+
+// notifyRemove handles a watched file that was removed or renamed away. The
+// check is delayed so an atomic save (write a temp file, rename it over the
+// original) counts as a single change of the original file.
+func notifyRemove(name string, changes *changeSet) {
+	time.AfterFunc(atomicSaveDelay, func() {
+		if tools.IsFile(name) {
+			notifyChange(name, changes)
+			return
+		}
+
+		forgetFile(name)
+		queueChange("Removed", name, changes)
+	})
+}
+
+// resyncNotify brings the watcher back in line with the project tree after
+// events were lost, and records every change that happened meanwhile
+func resyncNotify(watcher *fsnotify.Watcher, changes *changeSet) {
+	logger.Log.Warn("Event queue overflowed, rescanning watched tree...")
+
+	watchMutex.RLock()
+	dirs := make([]string, 0, len(watchedDirs))
+	for d := range watchedDirs {
+		dirs = append(dirs, d)
+	}
+	watchMutex.RUnlock()
+
+	for _, d := range dirs {
+		if !tools.IsDir(d) {
+			unwatchDir(watcher, d)
+		}
+	}
+	for _, root := range getWatchRoots() {
+		watchDir(watcher, root)
+	}
+
+	count := rescan(changes)
+	logger.Log.Infof("Rescan found %d changed file(s)", count)
+}
+
+// closeNotify closes the fsnotify watcher and forgets its watched directories
+func closeNotify(watcher *fsnotify.Watcher) {
+	watcher.Close()
+
+	watchMutex.Lock()
+	watchedDirs = make(map[string]bool)
+	watchMutex.Unlock()
+}
+
+// isWatchLimitError reports whether err means the system ran out of watches
+func isWatchLimitError(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EMFILE)
+}
+
+// watchDir adds dir and all of its unfiltered sub directories to the watcher.
+// Directories are watched even when they hold no matching file yet, so the
+// first file created in them is noticed. It returns the number of new watches
+// and the first error met while adding them.
+func watchDir(watcher *fsnotify.Watcher, dir string) (int, error) {
+	var firstErr error
+	count := 0
+	for _, d := range tools.GetPathDirFunc(dir, isFilterDir) {
+		if isWatchedDir(d) {
+			continue
+		}
+
+		if err := watcher.Add(d); err != nil {
+			logger.Log.Warnf("Failed to watch directory %s: %s", d, err)
+			logger.Log.Info("Tip: If you see 'too many open files', try: ulimit -n 2048")
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		watchMutex.Lock()
+		watchedDirs[d] = true
+		watchMutex.Unlock()
+
+		logger.Log.Hintf(colors.Bold("Watching: ")+"%s", d)
+		count++
+	}
+	return count, firstErr
+}
+
+// unwatchDir removes dir and every watched directory below it from the watcher.
+// It returns false when dir was not being watched.
+func unwatchDir(watcher *fsnotify.Watcher, dir string) bool {
+	if !isWatchedDir(dir) {
+		return false
+	}
+
+	prefix := dir + "/"
+	watchMutex.Lock()
+	for d := range watchedDirs {
+		if d == dir || strings.HasPrefix(d, prefix) {
+			// The kernel drops watches of deleted directories by itself,
+			// so errors here are expected and ignored.
+			watcher.Remove(d)
+			delete(watchedDirs, d)
+			logger.Log.Hintf(colors.Bold("Unwatching: ")+"%s", d)
+		}
+	}
+	watchMutex.Unlock()
+	return true
+}
+
+func isWatchedDir(dir string) bool {
+	watchMutex.RLock()
+	defer watchMutex.RUnlock()
+	return watchedDirs[dir]
+}
+
+// watchedDirCount returns the number of directories currently watched
+func watchedDirCount() int {
+	watchMutex.RLock()
+	defer watchMutex.RUnlock()
+	return len(watchedDirs)
+}
+
+// cachedFiles returns the watched files below dir known to fileCache
+func cachedFiles(dir string) []string {
+	prefix := dir + "/"
+	var files []string
+
+	cacheMutex.RLock()
+	for name := range fileCache {
+		if strings.HasPrefix(name, prefix) {
+			files = append(files, name)
+		}
+	}
+	cacheMutex.RUnlock()
+
+	var watched []string
+	for _, name := range files {
+		if !isFilterFile(name) {
+			watched = append(watched, name)
+		}
+	}
+	return watched
+}
+
+// forgetFile drops a file from fileCache
+func forgetFile(name string) {
+	cacheMutex.Lock()
+	delete(fileCache, name)
+	cacheMutex.Unlock()
+}
+
+// forgetDir drops every watched file below dir from fileCache and returns them
+func forgetDir(dir string) []string {
+	files := cachedFiles(dir)
+	for _, name := range files {
+		forgetFile(name)
+	}
+	return files
+}
+
+// newDirFiles returns the watched files already present below a newly created
+// directory; they may have been written before its watch was in place.
+func newDirFiles(dir string) []string {
+	var files []string
+	filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if fi.IsDir() {
+			if p != dir && isFilterDir(p) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isFilterFile(p) {
+			files = append(files, filepath.ToSlash(p))
+		}
+		return nil
+	})
+	return files
+}
+
+// initWatchRoots sets the project directory and the local modules it depends
+// on (go.mod replace, go.work, Cargo path dependencies) as watch roots
+func initWatchRoots(rootPath string) {
+	roots := []string{rootPath}
+	logger.Log.Infof(colors.Bold("Watch root: ")+"%s", rootPath)
+
+	for _, m := range workspace.LocalModules(rootPath) {
+		dir := filepath.ToSlash(m.Dir)
+		roots = append(roots, dir)
+		logger.Log.Infof(colors.Bold("Watch root: ")+"%s (%s)", dir, m.Source)
+	}
+
+	watchMutex.Lock()
+	watchRoots = roots
+	watchMutex.Unlock()
+}
+
+// getWatchRoots returns the directory trees being watched
+func getWatchRoots() []string {
+	watchMutex.RLock()
+	defer watchMutex.RUnlock()
+	return watchRoots
+}
@@ -0,0 +1,292 @@
+package diag
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/midoks/zzz/internal/logger/colors"
+)
+
+// Severities of a diagnostic
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityNote    = "note"
+)
+
+// Diagnostic is a single compiler or vet message pointing at a source position
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+var (
+	last      []Diagnostic
+	lastMutex sync.RWMutex
+)
+
+// SetLast stores the diagnostics of the latest build
+func SetLast(diags []Diagnostic) {
+	lastMutex.Lock()
+	last = diags
+	lastMutex.Unlock()
+}
+
+// Last returns the diagnostics of the latest build, empty after a clean build
+func Last() []Diagnostic {
+	lastMutex.RLock()
+	defer lastMutex.RUnlock()
+	return last
+}
+
+var goPosition = regexp.MustCompile(`^(.+?\.go):(\d+)(?::(\d+))?: (.*)$`)
+
+// ParseGo parses the output of the go compiler or go vet. Indented lines
+// continue the previous message, package headers ("# pkg") are skipped.
+func ParseGo(output string) []Diagnostic {
+	var diags []Diagnostic
+	s := bufio.NewScanner(strings.NewReader(output))
+	for s.Scan() {
+		line := s.Text()
+
+		if strings.HasPrefix(line, "\t") && len(diags) > 0 {
+			d := &diags[len(diags)-1]
+			d.Message += "\n" + strings.TrimSpace(line)
+			continue
+		}
+
+		m := goPosition.FindStringSubmatch(strings.TrimPrefix(line, "vet: "))
+		if m == nil {
+			continue
+		}
+
+		d := Diagnostic{
+			File:     filepath.ToSlash(filepath.Clean(m[1])),
+			Severity: SeverityError,
+			Message:  m[4],
+		}
+		d.Line, _ = strconv.Atoi(m[2])
+		d.Column, _ = strconv.Atoi(m[3])
+		diags = append(diags, d)
+	}
+	return diags
+}
+
+// Patterns of compilers reporting "file:line:col: severity: message" (gcc,
+// clang, zig) and of the TypeScript compiler
+var (
+	GCCPattern = regexp.MustCompile(`^(?P<file>[^\s:][^:]*):(?P<line>\d+):(?:(?P<col>\d+):)? (?P<severity>fatal error|error|warning|note): (?P<message>.*)$`)
+	TSCPattern = regexp.MustCompile(`^(?P<file>[^\s(][^(]*)\((?P<line>\d+),(?P<col>\d+)\): (?P<severity>error|warning) (?P<message>TS\d+: .*)$`)
+)
+
+// ParsePattern parses output with a line pattern using the named groups
+// file, line, col, severity and message. Lines without a severity are errors.
+func ParsePattern(output string, pattern *regexp.Regexp) []Diagnostic {
+	names := pattern.SubexpNames()
+
+	var diags []Diagnostic
+	s := bufio.NewScanner(strings.NewReader(output))
+	for s.Scan() {
+		m := pattern.FindStringSubmatch(s.Text())
+		if m == nil {
+			continue
+		}
+
+		d := Diagnostic{Severity: SeverityError}
+		for i, name := range names {
+			switch name {
+			case "file":
+				d.File = filepath.ToSlash(filepath.Clean(m[i]))
+			case "line":
+				d.Line, _ = strconv.Atoi(m[i])
+			case "col":
+				d.Column, _ = strconv.Atoi(m[i])
+			case "severity":
+				d.Severity = severity(m[i])
+			case "message":
+				d.Message = m[i]
+			}
+		}
+		if d.File == "" {
+			continue
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
+
+// severity maps a compiler severity word to one of the Severity constants
+func severity(word string) string {
+	word = strings.ToLower(word)
+	switch {
+	case strings.Contains(word, "warn"):
+		return SeverityWarning
+	case word == "note" || word == "info" || word == "hint":
+		return SeverityNote
+	}
+	return SeverityError
+}
+
+// ParseCargo parses complete cargo --message-format=json output
+func ParseCargo(output string) []Diagnostic {
+	var diags []Diagnostic
+	s := bufio.NewScanner(strings.NewReader(output))
+	s.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for s.Scan() {
+		if d, _, ok := ParseCargoLine(s.Text()); ok && d != nil {
+			diags = append(diags, *d)
+		}
+	}
+	return diags
+}
+
+// cargoMessage is the subset of a cargo --message-format=json line we use
+type cargoMessage struct {
+	Reason  string `json:"reason"`
+	Message struct {
+		Message  string `json:"message"`
+		Level    string `json:"level"`
+		Rendered string `json:"rendered"`
+		Spans    []struct {
+			FileName    string `json:"file_name"`
+			LineStart   int    `json:"line_start"`
+			ColumnStart int    `json:"column_start"`
+			IsPrimary   bool   `json:"is_primary"`
+		} `json:"spans"`
+	} `json:"message"`
+}
+
+// ParseCargoLine parses one line of cargo --message-format=json output. It
+// returns the diagnostic, if the line holds one, and the rendered compiler
+// message; ok is false for lines that are not cargo JSON messages.
+func ParseCargoLine(line string) (d *Diagnostic, rendered string, ok bool) {
+	if !strings.HasPrefix(line, "{") {
+		return nil, "", false
+	}
+
+	var msg cargoMessage
+	if err := json.Unmarshal([]byte(line), &msg); err != nil || msg.Reason == "" {
+		return nil, "", false
+	}
+	if msg.Reason != "compiler-message" {
+		return nil, "", true
+	}
+
+	rendered = msg.Message.Rendered
+	for _, span := range msg.Message.Spans {
+		if !span.IsPrimary {
+			continue
+		}
+
+		severity := SeverityNote
+		switch {
+		case strings.HasPrefix(msg.Message.Level, "error"):
+			severity = SeverityError
+		case msg.Message.Level == "warning":
+			severity = SeverityWarning
+		}
+
+		return &Diagnostic{
+			File:     filepath.ToSlash(span.FileName),
+			Line:     span.LineStart,
+			Column:   span.ColumnStart,
+			Severity: severity,
+			Message:  msg.Message.Message,
+		}, rendered, true
+	}
+	return nil, rendered, true
+}
+
+// Count returns the number of errors and warnings
+func Count(diags []Diagnostic) (errors, warnings int) {
+	for _, d := range diags {
+		switch d.Severity {
+		case SeverityError:
+			errors++
+		case SeverityWarning:
+			warnings++
+		}
+	}
+	return errors, warnings
+}
+
+// First returns the first error, or the first diagnostic without errors
+func First(diags []Diagnostic) *Diagnostic {
+	for i := range diags {
+		if diags[i].Severity == SeverityError {
+			return &diags[i]
+		}
+	}
+	if len(diags) > 0 {
+		return &diags[0]
+	}
+	return nil
+}
+
+// Position returns the file:line:col location of the diagnostic
+func (d Diagnostic) Position() string {
+	if d.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d", d.File, d.Line, d.Column)
+	}
+	return fmt.Sprintf("%s:%d", d.File, d.Line)
+}
+
+// Summary renders a compact, colorized summary grouped by file
+func Summary(diags []Diagnostic) string {
+	var (
+		files  []string
+		byFile = make(map[string][]Diagnostic)
+	)
+	for _, d := range diags {
+		if _, ok := byFile[d.File]; !ok {
+			files = append(files, d.File)
+		}
+		byFile[d.File] = append(byFile[d.File], d)
+	}
+
+	var b strings.Builder
+	for _, file := range files {
+		list := byFile[file]
+		sort.SliceStable(list, func(i, j int) bool {
+			if list[i].Line != list[j].Line {
+				return list[i].Line < list[j].Line
+			}
+			return list[i].Column < list[j].Column
+		})
+
+		b.WriteString(colors.Bold(file) + "\n")
+		for _, d := range list {
+			pos := fmt.Sprintf("%d:%d", d.Line, d.Column)
+			message := strings.Replace(d.Message, "\n", "\n"+strings.Repeat(" ", 21), -1)
+			b.WriteString(fmt.Sprintf("  %-8s %s  %s\n", pos, severityLabel(d.Severity), message))
+		}
+	}
+
+	errs, warnings := Count(diags)
+	b.WriteString(fmt.Sprintf("%d error(s), %d warning(s) in %d file(s)\n", errs, warnings, len(files)))
+	if first := First(diags); first != nil {
+		b.WriteString(colors.Bold("First: ") + first.Position() + "\n")
+	}
+	return b.String()
+}
+
+func severityLabel(severity string) string {
+	label := fmt.Sprintf("%-7s", severity)
+	switch severity {
+	case SeverityError:
+		return colors.Red(label)
+	case SeverityWarning:
+		return colors.Yellow(label)
+	}
+	return colors.Cyan(label)
+}
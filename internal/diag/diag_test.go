@@ -0,0 +1,194 @@
+package diag
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestParseGo(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []Diagnostic
+	}{
+		{
+			name:   "clean build",
+			output: "",
+		},
+		{
+			name: "compiler errors",
+			output: "# example.com/app\n" +
+				"./main.go:12:2: undefined: foo\n" +
+				"internal/store/store.go:40:15: cannot use x (variable of type int) as string value in return statement\n",
+			want: []Diagnostic{
+				{File: "main.go", Line: 12, Column: 2, Severity: SeverityError, Message: "undefined: foo"},
+				{File: "internal/store/store.go", Line: 40, Column: 15, Severity: SeverityError,
+					Message: "cannot use x (variable of type int) as string value in return statement"},
+			},
+		},
+		{
+			name: "continuation lines",
+			output: "./main.go:8:9: cannot use s (variable of type *server) as Handler value in argument to serve:\n" +
+				"\t*server does not implement Handler (missing method ServeHTTP)\n" +
+				"./main.go:9:1: missing return\n",
+			want: []Diagnostic{
+				{File: "main.go", Line: 8, Column: 9, Severity: SeverityError,
+					Message: "cannot use s (variable of type *server) as Handler value in argument to serve:\n*server does not implement Handler (missing method ServeHTTP)"},
+				{File: "main.go", Line: 9, Column: 1, Severity: SeverityError, Message: "missing return"},
+			},
+		},
+		{
+			name:   "vet without column",
+			output: "# example.com/app\nvet: ./main.go:5: fmt.Printf format %d has arg s of wrong type string\n",
+			want: []Diagnostic{
+				{File: "main.go", Line: 5, Severity: SeverityError, Message: "fmt.Printf format %d has arg s of wrong type string"},
+			},
+		},
+		{
+			name:   "link error",
+			output: "# example.com/app\n/usr/bin/ld: cannot find -lfoo\ncollect2: error: ld returned 1 exit status\n",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := ParseGo(tt.output); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParseGo() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseCargo(t *testing.T) {
+	output := `{"reason":"compiler-artifact","package_id":"dep 0.1.0"}
+{"reason":"compiler-message","message":{"message":"mismatched types","level":"error","rendered":"error[E0308]: mismatched types\n --> src/main.rs:3:5\n","spans":[{"file_name":"src/lib.rs","line_start":1,"line_end":1,"column_start":1,"is_primary":false},{"file_name":"src/main.rs","line_start":3,"line_end":7,"column_start":5,"is_primary":true}]}}
+{"reason":"compiler-message","message":{"message":"unused variable: ` + "`x`" + `","level":"warning","rendered":"warning: unused variable","spans":[{"file_name":"src/util.rs","line_start":10,"line_end":10,"column_start":9,"is_primary":true}]}}
+{"reason":"compiler-message","message":{"message":"aborting due to 1 previous error","level":"error","rendered":"error: aborting","spans":[]}}
+{"reason":"compiler-message","message":{"message":"this function is never used","level":"error: internal compiler error","rendered":"","spans":[{"file_name":"src/ice.rs","line_start":2,"line_end":2,"column_start":1,"is_primary":true}]}}
+   Compiling app v0.1.0
+{"reason":"build-finished","success":false}
+`
+
+	want := []Diagnostic{
+		// The primary span of a multi-line span is reported at its start
+		{File: "src/main.rs", Line: 3, Column: 5, Severity: SeverityError, Message: "mismatched types"},
+		{File: "src/util.rs", Line: 10, Column: 9, Severity: SeverityWarning, Message: "unused variable: `x`"},
+		{File: "src/ice.rs", Line: 2, Column: 1, Severity: SeverityError, Message: "this function is never used"},
+	}
+	if got := ParseCargo(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCargo() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseCargoLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		wantDiag bool
+		rendered string
+		ok       bool
+	}{
+		{line: "   Compiling app v0.1.0", ok: false},
+		{line: "{not json", ok: false},
+		{line: `{"other":"json"}`, ok: false},
+		{line: `{"reason":"build-finished","success":true}`, ok: true},
+		{
+			line:     `{"reason":"compiler-message","message":{"message":"m","level":"error","rendered":"error: m\n","spans":[]}}`,
+			rendered: "error: m\n",
+			ok:       true,
+		},
+		{
+			line:     `{"reason":"compiler-message","message":{"message":"m","level":"note","rendered":"note: m","spans":[{"file_name":"a.rs","line_start":1,"column_start":1,"is_primary":true}]}}`,
+			wantDiag: true,
+			rendered: "note: m",
+			ok:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		d, rendered, ok := ParseCargoLine(tt.line)
+		if (d != nil) != tt.wantDiag || rendered != tt.rendered || ok != tt.ok {
+			t.Errorf("ParseCargoLine(%q) = %v, %q, %v, want diagnostic %v, %q, %v",
+				tt.line, d, rendered, ok, tt.wantDiag, tt.rendered, tt.ok)
+		}
+	}
+}
+
+func TestCountAndFirst(t *testing.T) {
+	diags := []Diagnostic{
+		{File: "a.go", Line: 1, Severity: SeverityWarning},
+		{File: "b.go", Line: 2, Column: 3, Severity: SeverityError},
+		{File: "c.go", Line: 4, Severity: SeverityNote},
+		{File: "d.go", Line: 5, Severity: SeverityError},
+	}
+
+	errs, warnings := Count(diags)
+	if errs != 2 || warnings != 1 {
+		t.Errorf("Count() = %d, %d, want 2, 1", errs, warnings)
+	}
+	if first := First(diags); first == nil || first.Position() != "b.go:2:3" {
+		t.Errorf("First() = %v, want b.go:2:3", first)
+	}
+	if first := First(diags[:1]); first == nil || first.Position() != "a.go:1" {
+		t.Errorf("First() without errors = %v, want a.go:1", first)
+	}
+	if first := First(nil); first != nil {
+		t.Errorf("First(nil) = %v, want nil", first)
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		pattern *regexp.Regexp
+		want    []Diagnostic
+	}{
+		{
+			name: "gcc",
+			output: "main.c: In function 'main':\n" +
+				"main.c:4:5: error: unknown type name 'foo'\n" +
+				"    4 |     foo x;\n" +
+				"./src/util.c:10:1: warning: control reaches end of non-void function [-Wreturn-type]\n" +
+				"src/util.h:2: note: declared here\n" +
+				"main.c:1:10: fatal error: missing.h: No such file or directory\n" +
+				"collect2: error: ld returned 1 exit status\n",
+			pattern: GCCPattern,
+			want: []Diagnostic{
+				{File: "main.c", Line: 4, Column: 5, Severity: SeverityError, Message: "unknown type name 'foo'"},
+				{File: "src/util.c", Line: 10, Column: 1, Severity: SeverityWarning, Message: "control reaches end of non-void function [-Wreturn-type]"},
+				{File: "src/util.h", Line: 2, Severity: SeverityNote, Message: "declared here"},
+				{File: "main.c", Line: 1, Column: 10, Severity: SeverityError, Message: "missing.h: No such file or directory"},
+			},
+		},
+		{
+			name:    "zig",
+			output:  "src/main.zig:3:5: error: use of undeclared identifier 'x'\n    x += 1;\n    ^\n",
+			pattern: GCCPattern,
+			want: []Diagnostic{
+				{File: "src/main.zig", Line: 3, Column: 5, Severity: SeverityError, Message: "use of undeclared identifier 'x'"},
+			},
+		},
+		{
+			name: "tsc",
+			output: "src/index.ts(12,7): error TS2322: Type 'string' is not assignable to type 'number'.\n" +
+				"Found 1 error.\n",
+			pattern: TSCPattern,
+			want: []Diagnostic{
+				{File: "src/index.ts", Line: 12, Column: 7, Severity: SeverityError, Message: "TS2322: Type 'string' is not assignable to type 'number'."},
+			},
+		},
+		{
+			name:    "custom without severity",
+			output:  "ERR lib/a.lua line 7: unexpected symbol\n",
+			pattern: regexp.MustCompile(`^ERR (?P<file>\S+) line (?P<line>\d+): (?P<message>.*)$`),
+			want: []Diagnostic{
+				{File: "lib/a.lua", Line: 7, Severity: SeverityError, Message: "unexpected symbol"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := ParsePattern(tt.output, tt.pattern); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParsePattern() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
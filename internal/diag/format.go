@@ -0,0 +1,95 @@
+package diag
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Output formats of the diagnostics file
+const (
+	FormatQuickfix = "quickfix"
+	FormatPlain    = "plain"
+	FormatJSON     = "json"
+)
+
+// IsFormat reports whether format is a known output format
+func IsFormat(format string) bool {
+	switch format {
+	case FormatQuickfix, FormatPlain, FormatJSON:
+		return true
+	}
+	return false
+}
+
+// Format renders diagnostics for editors:
+//
+//	quickfix  file:line:col: error: message  (errorformat %f:%l:%c:\ %t%*[^:]:\ %m)
+//	plain     file:line:col: message
+//	json      [{"file": ..., "line": ..., "column": ..., "severity": ..., "message": ...}]
+//
+// Diagnostics without a file, such as linker errors, render as "error: message"
+// and "message". No diagnostics render as an empty file, or an empty array for json.
+func Format(diags []Diagnostic, format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		if diags == nil {
+			diags = []Diagnostic{}
+		}
+		data, err := json.MarshalIndent(diags, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+
+	case FormatQuickfix, FormatPlain:
+		var b strings.Builder
+		for _, d := range diags {
+			// Continuation lines would break line based parsers
+			message := strings.Replace(d.Message, "\n", " ", -1)
+			switch {
+			case d.File == "" && format == FormatQuickfix:
+				b.WriteString(fmt.Sprintf("%s: %s\n", d.Severity, message))
+			case d.File == "":
+				b.WriteString(message + "\n")
+			case format == FormatQuickfix:
+				b.WriteString(fmt.Sprintf("%s:%d:%d: %s: %s\n", d.File, d.Line, d.Column, d.Severity, message))
+			default:
+				b.WriteString(fmt.Sprintf("%s:%d:%d: %s\n", d.File, d.Line, d.Column, message))
+			}
+		}
+		return []byte(b.String()), nil
+	}
+	return nil, fmt.Errorf("unknown diagnostics format: %s", format)
+}
+
+// WriteFile writes diagnostics to file in the given format. The file is
+// replaced atomically so editors never read a partial list, and left alone
+// when its content would not change.
+func WriteFile(file, format string, diags []Diagnostic) error {
+	data, err := Format(diags, format)
+	if err != nil {
+		return err
+	}
+	if old, err := os.ReadFile(file); err == nil && bytes.Equal(old, data) {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	tmp := TempFile(file)
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// TempFile returns the temporary file WriteFile renames to file
+func TempFile(file string) string {
+	return file + ".tmp"
+}
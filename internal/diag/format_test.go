@@ -0,0 +1,94 @@
+package diag
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	diags := []Diagnostic{
+		{File: "main.go", Line: 3, Column: 5, Severity: SeverityError, Message: "undefined: x"},
+		{File: "util.go", Line: 7, Severity: SeverityWarning, Message: "first line\nsecond line"},
+		{Severity: SeverityError, Message: "Build failed: ld: cannot find -lfoo"},
+	}
+
+	tests := []struct {
+		format string
+		diags  []Diagnostic
+		want   string
+	}{
+		{
+			format: FormatQuickfix,
+			diags:  diags,
+			want: "main.go:3:5: error: undefined: x\n" +
+				"util.go:7:0: warning: first line second line\n" +
+				"error: Build failed: ld: cannot find -lfoo\n",
+		},
+		{
+			format: FormatPlain,
+			diags:  diags,
+			want: "main.go:3:5: undefined: x\n" +
+				"util.go:7:0: first line second line\n" +
+				"Build failed: ld: cannot find -lfoo\n",
+		},
+		{
+			format: FormatJSON,
+			diags:  diags[:1],
+			want: "[\n  {\n    \"file\": \"main.go\",\n    \"line\": 3,\n    \"column\": 5,\n" +
+				"    \"severity\": \"error\",\n    \"message\": \"undefined: x\"\n  }\n]\n",
+		},
+		{format: FormatQuickfix, want: ""},
+		{format: FormatJSON, want: "[]\n"},
+	}
+
+	for _, tt := range tests {
+		got, err := Format(tt.diags, tt.format)
+		if err != nil {
+			t.Errorf("Format(%s) error: %s", tt.format, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("Format(%s) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+
+	if _, err := Format(diags, "xml"); err == nil {
+		t.Error("Format(xml) error = nil, want unknown format")
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "out", "errors.txt")
+	diags := []Diagnostic{{File: "main.go", Line: 1, Column: 1, Severity: SeverityError, Message: "boom"}}
+
+	if err := WriteFile(file, FormatQuickfix, diags); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "main.go:1:1: error: boom\n" {
+		t.Errorf("WriteFile content = %q", data)
+	}
+	if _, err := os.Stat(TempFile(file)); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	// Unchanged content leaves the file alone, so watchers see no change
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(file, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(file, FormatQuickfix, diags); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(file); err != nil || !fi.ModTime().Equal(old) {
+		t.Errorf("unchanged diagnostics rewrote the file")
+	}
+
+	if err := WriteFile(file, FormatQuickfix, nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(file); len(data) != 0 {
+		t.Errorf("WriteFile without diagnostics = %q, want empty", data)
+	}
+}
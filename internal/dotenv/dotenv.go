@@ -0,0 +1,123 @@
+package dotenv
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Load reads a dotenv file. Values may reference variables defined earlier in
+// the file or returned by lookup.
+func Load(file string, lookup func(string) (string, bool)) ([]string, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(content), lookup)
+}
+
+// Parse parses dotenv content into KEY=VALUE pairs, in file order:
+//
+//	# comment
+//	export KEY=value        the export prefix is optional
+//	KEY=value # comment     unquoted values are trimmed and expanded
+//	KEY="a ${B:-b}\n"       double quotes expand variables and escapes
+//	KEY='$literal'          single quotes keep the value as is
+//
+// Double and single quoted values may span several lines.
+func Parse(content string, lookup func(string) (string, bool)) ([]string, error) {
+	values := make(map[string]string)
+	get := func(key string) (string, bool) {
+		if v, ok := values[key]; ok {
+			return v, true
+		}
+		if lookup != nil {
+			return lookup(key)
+		}
+		return "", false
+	}
+
+	var env []string
+	s := bufio.NewScanner(strings.NewReader(content))
+	lineNo := 0
+	for s.Scan() {
+		lineNo++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNo)
+		}
+		key := strings.TrimSpace(line[:i])
+		raw := strings.TrimSpace(line[i+1:])
+
+		var value string
+		if raw != "" && (raw[0] == '"' || raw[0] == '\'') {
+			quote := raw[0]
+			// Read further lines until the closing quote
+			for !closed(raw, quote) {
+				if !s.Scan() {
+					return nil, fmt.Errorf("line %d: unterminated quoted value", lineNo)
+				}
+				lineNo++
+				raw += "\n" + s.Text()
+			}
+			end := closingQuote(raw, quote)
+			value = raw[1:end]
+			if quote == '"' {
+				value = expand(unescape(value), get)
+			}
+		} else {
+			if j := strings.Index(raw, " #"); j >= 0 {
+				raw = strings.TrimSpace(raw[:j])
+			}
+			value = expand(raw, get)
+		}
+
+		values[key] = value
+		env = append(env, key+"="+value)
+	}
+	return env, s.Err()
+}
+
+// closed reports whether the quoted value starting at raw[0] is terminated
+func closed(raw string, quote byte) bool {
+	return closingQuote(raw, quote) > 0
+}
+
+// closingQuote returns the index of the quote ending the value, or -1
+func closingQuote(raw string, quote byte) int {
+	for i := 1; i < len(raw); i++ {
+		switch {
+		case raw[i] == '\\' && quote == '"':
+			i++
+		case raw[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+func unescape(s string) string {
+	r := strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`)
+	return r.Replace(s)
+}
+
+// expand replaces $VAR, ${VAR} and ${VAR:-default} references
+func expand(s string, get func(string) (string, bool)) string {
+	return os.Expand(s, func(name string) string {
+		if i := strings.Index(name, ":-"); i >= 0 {
+			if v, ok := get(name[:i]); ok && v != "" {
+				return v
+			}
+			return name[i+2:]
+		}
+		v, _ := get(name)
+		return v
+	})
+}
@@ -0,0 +1,125 @@
+package dotenv
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	lookup := func(key string) (string, bool) {
+		switch key {
+		case "HOME":
+			return "/home/me", true
+		case "EMPTY":
+			return "", true
+		}
+		return "", false
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "comments and blank lines",
+			content: "# comment\n\n  # indented comment\nA=1\n",
+			want:    []string{"A=1"},
+		},
+		{
+			name:    "export prefix and spaces",
+			content: "export A=1\n  B = two words  \n",
+			want:    []string{"A=1", "B=two words"},
+		},
+		{
+			name:    "inline comment",
+			content: "A=value # comment\nB=no#comment\n",
+			want:    []string{"A=value", "B=no#comment"},
+		},
+		{
+			name:    "empty value",
+			content: "A=\nB=\"\"\n",
+			want:    []string{"A=", "B="},
+		},
+		{
+			name:    "double quotes expand escapes",
+			content: `A="line\nnext\ttab \"q\" \\ # not a comment"` + "\n",
+			want:    []string{"A=line\nnext\ttab \"q\" \\ # not a comment"},
+		},
+		{
+			name:    "single quotes are literal",
+			content: `A='$HOME\n ${B} "q"'` + "\n",
+			want:    []string{`A=$HOME\n ${B} "q"`},
+		},
+		{
+			name:    "multi-line values",
+			content: "A=\"first\nsecond\"\nB='x\ny'\nC=3\n",
+			want:    []string{"A=first\nsecond", "B=x\ny", "C=3"},
+		},
+		{
+			name:    "expansion",
+			content: "A=$HOME/bin\nB=${A}:/usr/bin\nC=\"${MISSING:-fallback}\"\nD=${EMPTY:-default}\nE=${MISSING}\n",
+			want:    []string{"A=/home/me/bin", "B=/home/me/bin:/usr/bin", "C=fallback", "D=default", "E="},
+		},
+		{
+			name:    "file values override lookup",
+			content: "HOME=/root\nA=$HOME\n",
+			want:    []string{"HOME=/root", "A=/root"},
+		},
+		{
+			name:    "equals in value",
+			content: "URL=postgres://u:p@host/db?sslmode=disable\n",
+			want:    []string{"URL=postgres://u:p@host/db?sslmode=disable"},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.content, lookup)
+		if err != nil {
+			t.Errorf("%s: Parse() error: %s", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Parse() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"missing equals", "A=1\nNOVALUE\n"},
+		{"missing key", "=1\n"},
+		{"unterminated double quote", "A=\"open\nstill open\n"},
+		{"unterminated single quote", "A='open\n"},
+	}
+
+	for _, tt := range tests {
+		if _, err := Parse(tt.content, nil); err == nil {
+			t.Errorf("%s: Parse() error = nil", tt.name)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(file, []byte("A=1\nB=${A}2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Load(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"A=1", "B=12"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() = %q, want %q", got, want)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing"), nil); err == nil {
+		t.Error("Load() of a missing file error = nil")
+	}
+}
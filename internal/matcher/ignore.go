@@ -0,0 +1,67 @@
+package matcher
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignored reports whether rel is ignored by the .gitignore/.ignore files found
+// in the root and in every directory between the root and rel.
+func (m *Matcher) ignored(rel string, isDir bool) bool {
+	ignored := false
+	dirs := append([]string{"."}, parents(rel)...)
+	for _, dir := range dirs {
+		sub := rel
+		if dir != "." {
+			sub = strings.TrimPrefix(rel, dir+"/")
+		}
+		for _, r := range m.ignoreRules(dir) {
+			if r.match(sub, isDir) {
+				ignored = !r.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// ignoreRules returns the cached rules of the ignore files in dir
+func (m *Matcher) ignoreRules(dir string) []rule {
+	m.mutex.RLock()
+	rules, ok := m.ignores[dir]
+	m.mutex.RUnlock()
+	if ok {
+		return rules
+	}
+
+	for _, name := range IgnoreFiles {
+		rules = append(rules, readIgnoreFile(filepath.Join(m.root, filepath.FromSlash(path.Join(dir, name))))...)
+	}
+
+	m.mutex.Lock()
+	m.ignores[dir] = rules
+	m.mutex.Unlock()
+	return rules
+}
+
+// readIgnoreFile parses a gitignore-style file, missing files yield no rules
+func readIgnoreFile(file string) []rule {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules []rule
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		r := compileRule(s.Text())
+		if r.pattern == "" {
+			continue
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
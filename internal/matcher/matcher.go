@@ -0,0 +1,229 @@
+package matcher
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Matcher decides which files and directories of a project are watched.
+// Patterns are gitignore-style globs relative to the project root:
+//
+//	**/*.go        any .go file at any depth
+//	!**/*_mock.go  negation, the last matching pattern wins
+//	web/dist/**    everything below web/dist
+type Matcher struct {
+	root      string
+	include   []rule
+	exclude   []rule
+	gitignore bool
+
+	mutex   sync.RWMutex
+	ignores map[string][]rule
+}
+
+// rule is a single compiled glob pattern
+type rule struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// IgnoreFiles lists the per-directory ignore files honoured when gitignore support is enabled
+var IgnoreFiles = []string{".gitignore", ".ignore"}
+
+// New creates a matcher for the project rooted at root
+func New(root string, include, exclude []string, gitignore bool) *Matcher {
+	return &Matcher{
+		root:      filepath.Clean(root),
+		include:   compile(include),
+		exclude:   compile(exclude),
+		gitignore: gitignore,
+		ignores:   make(map[string][]rule),
+	}
+}
+
+// HasInclude reports whether include patterns are configured
+func (m *Matcher) HasInclude() bool {
+	return len(m.include) > 0
+}
+
+// Included reports whether the file matches the include patterns.
+// A matcher without include patterns includes every file.
+func (m *Matcher) Included(name string) bool {
+	if len(m.include) == 0 {
+		return true
+	}
+	rel, ok := m.rel(name)
+	if !ok {
+		return false
+	}
+	return matchRules(m.include, rel, false)
+}
+
+// Excluded reports whether a file or directory is excluded by the exclude
+// patterns or, when enabled, by .gitignore/.ignore files.
+func (m *Matcher) Excluded(name string, isDir bool) bool {
+	rel, ok := m.rel(name)
+	if !ok || rel == "." {
+		return false
+	}
+
+	// A path below an excluded directory is excluded as well
+	for _, dir := range parents(rel) {
+		if m.excluded(dir, true) {
+			return true
+		}
+	}
+	return m.excluded(rel, isDir)
+}
+
+func (m *Matcher) excluded(rel string, isDir bool) bool {
+	if matchRules(m.exclude, rel, isDir) {
+		return true
+	}
+	return m.gitignore && m.ignored(rel, isDir)
+}
+
+// SkipDir reports whether a directory should not be descended into
+func (m *Matcher) SkipDir(dir string) bool {
+	return m.Excluded(dir, true)
+}
+
+// MatchFile reports whether a changed file should be watched
+func (m *Matcher) MatchFile(name string) bool {
+	return m.Included(name) && !m.Excluded(name, false)
+}
+
+// Reset drops cached ignore files so they are re-read on next use
+func (m *Matcher) Reset() {
+	m.mutex.Lock()
+	m.ignores = make(map[string][]rule)
+	m.mutex.Unlock()
+}
+
+// IsIgnoreFile reports whether name is one of the ignore files
+func IsIgnoreFile(name string) bool {
+	base := filepath.Base(name)
+	for _, f := range IgnoreFiles {
+		if base == f {
+			return true
+		}
+	}
+	return false
+}
+
+// rel returns the slash-separated path of name relative to the root
+func (m *Matcher) rel(name string) (string, bool) {
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(m.root, name)
+	}
+	rel, err := filepath.Rel(m.root, name)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// parents returns the ancestor directories of rel, outermost first
+func parents(rel string) []string {
+	var dirs []string
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		dirs = append(dirs, strings.Join(parts[:i], "/"))
+	}
+	return dirs
+}
+
+func compile(patterns []string) []rule {
+	rules := make([]rule, 0, len(patterns))
+	for _, p := range patterns {
+		r := compileRule(p)
+		if r.pattern == "" {
+			continue
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+func compileRule(p string) rule {
+	p = strings.TrimSpace(filepath.ToSlash(p))
+	if p == "" || strings.HasPrefix(p, "#") {
+		return rule{}
+	}
+
+	r := rule{}
+	if strings.HasPrefix(p, "!") {
+		r.negate = true
+		p = p[1:]
+	}
+	if strings.HasSuffix(p, "/") {
+		r.dirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+	p = strings.TrimPrefix(p, "./")
+	if strings.HasPrefix(p, "/") {
+		r.anchored = true
+		p = strings.TrimLeft(p, "/")
+	} else if strings.Contains(p, "/") {
+		r.anchored = true
+	}
+	r.pattern = p
+	return r
+}
+
+// matchRules applies rules in order; the last matching rule decides
+func matchRules(rules []rule, rel string, isDir bool) bool {
+	matched := false
+	for _, r := range rules {
+		if r.match(rel, isDir) {
+			matched = !r.negate
+		}
+	}
+	return matched
+}
+
+func (r rule) match(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+
+	if !r.anchored {
+		return matchSegment(r.pattern, path.Base(rel))
+	}
+
+	return matchSegments(strings.Split(r.pattern, "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			// A trailing "**" also covers the directory itself so it can be pruned
+			if len(rest) == 0 {
+				return true
+			}
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(parts) == 0 || !matchSegment(pattern[0], parts[0]) {
+			return false
+		}
+		pattern = pattern[1:]
+		parts = parts[1:]
+	}
+	return len(parts) == 0
+}
+
+func matchSegment(pattern, name string) bool {
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
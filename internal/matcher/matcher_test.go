@@ -0,0 +1,188 @@
+package matcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRuleMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		isDir   bool
+		want    bool
+	}{
+		// Patterns without a slash match the base name at any depth
+		{"*.go", "main.go", false, true},
+		{"*.go", "cmd/zzz/main.go", false, true},
+		{"*.go", "main.rs", false, false},
+
+		// Patterns with a slash are anchored to the root
+		{"cmd/*.go", "cmd/main.go", false, true},
+		{"cmd/*.go", "sub/cmd/main.go", false, false},
+		{"/main.go", "main.go", false, true},
+		{"/main.go", "sub/main.go", false, false},
+		{"./main.go", "main.go", false, true},
+
+		// "**" matches zero or more segments
+		{"**/*.go", "main.go", false, true},
+		{"**/*.go", "a/b/c/main.go", false, true},
+		{"web/**/*.js", "web/app.js", false, true},
+		{"web/**/*.js", "web/src/lib/app.js", false, true},
+		{"web/dist/**", "web/dist", true, true},
+		{"web/dist/**", "web/dist/app.js", false, true},
+		{"web/dist/**", "web/src/app.js", false, false},
+
+		// Trailing slash only matches directories
+		{"build/", "build", true, true},
+		{"build/", "build", false, false},
+		{"tmp/", "a/tmp", true, true},
+
+		// Comments and blank lines never match
+		{"# main.go", "main.go", false, false},
+		{"", "main.go", false, false},
+	}
+
+	for _, tt := range tests {
+		r := compileRule(tt.pattern)
+		got := r.pattern != "" && r.match(tt.name, tt.isDir)
+		if got != tt.want {
+			t.Errorf("rule %q match(%q, dir=%v) = %v, want %v", tt.pattern, tt.name, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestMatchRulesNegation(t *testing.T) {
+	rules := compile([]string{"**/*.go", "!**/*_mock.go", "internal/gen/*_mock.go"})
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"main.go", true},
+		{"store_mock.go", false},
+		{"internal/store/store_mock.go", false},
+		// The last matching rule wins
+		{"internal/gen/store_mock.go", true},
+	}
+
+	for _, tt := range tests {
+		if got := matchRules(rules, tt.name, false); got != tt.want {
+			t.Errorf("matchRules(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	root := t.TempDir()
+	m := New(root, []string{"**/*.go"}, []string{"vendor/", "**/*_test.go"}, false)
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"main.go", true},
+		{filepath.Join(root, "cmd", "main.go"), true},
+		{"main_test.go", false},
+		{"vendor/lib/lib.go", false},
+		{"README.md", false},
+		// Outside the root
+		{filepath.Join(filepath.Dir(root), "main.go"), false},
+	}
+
+	for _, tt := range tests {
+		if got := m.MatchFile(tt.name); got != tt.want {
+			t.Errorf("MatchFile(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if !m.SkipDir(filepath.Join(root, "vendor")) {
+		t.Error("SkipDir(vendor) = false, want true")
+	}
+	if m.SkipDir(filepath.Join(root, "cmd")) {
+		t.Error("SkipDir(cmd) = true, want false")
+	}
+}
+
+func TestGitignore(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(".gitignore", "# build output\n/bin\n*.log\n!keep.log\nnode_modules/\ndocs/*.html\n")
+	write("web/.gitignore", "dist/\n")
+	write("web/.ignore", "*.map\n")
+
+	m := New(root, nil, nil, true)
+	tests := []struct {
+		name  string
+		isDir bool
+		want  bool
+	}{
+		{"main.go", false, false},
+		{"bin", true, true},
+		// Anchored to the directory of the ignore file
+		{"sub/bin", true, false},
+		{"debug.log", false, true},
+		{"sub/debug.log", false, true},
+		// Negation re-includes a file
+		{"keep.log", false, false},
+		{"node_modules", true, true},
+		{"web/node_modules/lib/index.js", false, true},
+		// Dir-only patterns do not match files of that name
+		{"node_modules", false, false},
+		{"docs/index.html", false, true},
+		{"docs/api/index.html", false, false},
+		// Nested ignore files apply below their directory only
+		{"web/dist", true, true},
+		{"web/dist/app.js", false, true},
+		{"dist", true, false},
+		{"web/app.js.map", false, true},
+		{"app.js.map", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := m.Excluded(tt.name, tt.isDir); got != tt.want {
+			t.Errorf("Excluded(%q, dir=%v) = %v, want %v", tt.name, tt.isDir, got, tt.want)
+		}
+	}
+
+	// Ignore files are cached until Reset
+	write(".gitignore", "*.tmp\n")
+	if !m.Excluded("debug.log", false) {
+		t.Error("Excluded(debug.log) = false before Reset, want cached true")
+	}
+	m.Reset()
+	if m.Excluded("debug.log", false) {
+		t.Error("Excluded(debug.log) = true after Reset, want false")
+	}
+	if !m.Excluded("x.tmp", false) {
+		t.Error("Excluded(x.tmp) = false after Reset, want true")
+	}
+}
+
+func TestIsIgnoreFile(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{".gitignore", true},
+		{"/src/web/.ignore", true},
+		{"gitignore", false},
+		{".gitignore.bak", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsIgnoreFile(tt.name); got != tt.want {
+			t.Errorf("IsIgnoreFile(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package netstat
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Process is a process holding a socket
+type Process struct {
+	PID  int
+	Name string
+}
+
+func (p Process) String() string {
+	if p.Name == "" {
+		return fmt.Sprintf("PID %d", p.PID)
+	}
+	return fmt.Sprintf("PID %d (%s)", p.PID, p.Name)
+}
+
+// tcpListen is the socket state of a listening socket in /proc/net/tcp
+const tcpListen = "0A"
+
+// PortFree reports whether a listener can be bound to the TCP port
+func PortFree(port int) bool {
+	l, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
+
+// WaitPortFree waits until the TCP port can be bound or the timeout expires
+func WaitPortFree(port int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if PortFree(port) {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// Listeners returns the processes listening on the TCP port. It reads
+// /proc/net/tcp and /proc/net/tcp6 and so only works on Linux.
+func Listeners(port int) ([]Process, error) {
+	inodes := make(map[string]bool)
+	found := false
+	for _, file := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		err := listenInodes(file, port, inodes)
+		if err == nil {
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("/proc/net/tcp is not available")
+	}
+	if len(inodes) == 0 {
+		return nil, nil
+	}
+
+	fds, _ := filepath.Glob("/proc/[0-9]*/fd/[0-9]*")
+	seen := make(map[int]bool)
+	var procs []Process
+	for _, fd := range fds {
+		link, err := os.Readlink(fd)
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+		if !inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] {
+			continue
+		}
+
+		pid, _ := strconv.Atoi(strings.Split(fd, "/")[2])
+		if seen[pid] {
+			continue
+		}
+		seen[pid] = true
+
+		name, _ := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "comm"))
+		procs = append(procs, Process{PID: pid, Name: strings.TrimSpace(string(name))})
+	}
+	return procs, nil
+}
+
+// listenInodes adds the inodes of the sockets listening on port in a
+// /proc/net/tcp style file
+func listenInodes(file string, port int, inodes map[string]bool) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Scan() // header
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 10 || fields[3] != tcpListen {
+			continue
+		}
+
+		i := strings.LastIndex(fields[1], ":")
+		if i < 0 {
+			continue
+		}
+		p, err := strconv.ParseInt(fields[1][i+1:], 16, 32)
+		if err != nil || int(p) != port {
+			continue
+		}
+		inodes[fields[9]] = true
+	}
+	return s.Err()
+}
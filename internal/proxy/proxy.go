@@ -0,0 +1,241 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventsPath is the server-sent events endpoint of the live reload script
+const EventsPath = "/__zzz/events"
+
+// retryInterval is how often a refused request to the app is retried
+const retryInterval = 100 * time.Millisecond
+
+// script reloads the page when the proxy sends a reload event. EventSource
+// reconnects on its own while zzz restarts.
+const script = `<script>(function(){var s=new EventSource("` + EventsPath + `");` +
+	`s.addEventListener("reload",function(){location.reload()});})();</script>`
+
+// Server is a development reverse proxy in front of the app. It injects a
+// live reload script into HTML pages, holds requests while the app restarts
+// and tells browsers to reload once the new instance is ready.
+type Server struct {
+	target  *url.URL
+	timeout time.Duration
+	proxy   *httputil.ReverseProxy
+
+	mutex   sync.Mutex
+	ready   chan struct{}
+	isReady bool
+	clients map[chan struct{}]bool
+}
+
+// New creates a proxy forwarding to target. Requests wait up to timeout for
+// the app to become ready.
+func New(target string, timeout time.Duration) (*Server, error) {
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid target '%s'", target)
+	}
+
+	s := &Server{
+		target:  u,
+		timeout: timeout,
+		ready:   make(chan struct{}),
+		clients: make(map[chan struct{}]bool),
+	}
+
+	s.proxy = httputil.NewSingleHostReverseProxy(u)
+	director := s.proxy.Director
+	s.proxy.Director = func(r *http.Request) {
+		director(r)
+		// Keep HTML uncompressed so the script can be injected
+		r.Header.Del("Accept-Encoding")
+	}
+	s.proxy.Transport = &retryTransport{server: s, base: http.DefaultTransport}
+	s.proxy.ModifyResponse = injectScript
+	s.proxy.ErrorHandler = s.unavailable
+	return s, nil
+}
+
+// ListenAndServe serves the proxy on addr
+func (s *Server) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(EventsPath, s.events)
+	mux.HandleFunc("/", s.serve)
+	return http.ListenAndServe(addr, mux)
+}
+
+// Pause holds new requests, the app is restarting
+func (s *Server) Pause() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.isReady {
+		s.isReady = false
+		s.ready = make(chan struct{})
+	}
+}
+
+// Ready releases held requests and makes connected browsers reload
+func (s *Server) Ready() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.isReady {
+		s.isReady = true
+		close(s.ready)
+	}
+	for ch := range s.clients {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Clients returns the number of connected browsers
+func (s *Server) Clients() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.clients)
+}
+
+// wait blocks until the app is ready, the timeout expires or ctx is done
+func (s *Server) wait(ctx context.Context, timeout time.Duration) bool {
+	s.mutex.Lock()
+	ready := s.ready
+	s.mutex.Unlock()
+
+	select {
+	case <-ready:
+		return true
+	case <-ctx.Done():
+	case <-time.After(timeout):
+	}
+	return false
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.wait(r.Context(), s.timeout)
+	s.proxy.ServeHTTP(w, r)
+}
+
+// events streams reload events to a browser
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan struct{}, 1)
+	s.mutex.Lock()
+	s.clients[ch] = true
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.clients, ch)
+		s.mutex.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(w, "retry: 500\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(30 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ch:
+			fmt.Fprint(w, "event: reload\ndata: reload\n\n")
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// unavailable answers when the app could not be reached in time, with a page
+// that reloads itself once the app is back
+func (s *Server) unavailable(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	fmt.Fprintf(w, "<!DOCTYPE html><html><body><h3>zzz: %s is not available</h3><pre>%s</pre>%s</body></html>",
+		s.target.Host, err, script)
+}
+
+// retryTransport retries requests refused by the app while it restarts
+type retryTransport struct {
+	server *Server
+	base   http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	// Requests with a body can only be sent once
+	if r.Body != nil && r.Body != http.NoBody {
+		return t.base.RoundTrip(r)
+	}
+
+	deadline := time.Now().Add(t.server.timeout)
+	for {
+		resp, err := t.base.RoundTrip(r)
+		if err == nil || !isRefused(err) || time.Now().After(deadline) {
+			return resp, err
+		}
+
+		// Returns early once the next instance is ready
+		t.server.wait(r.Context(), retryInterval)
+		if err := r.Context().Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// isRefused reports whether err means nothing listens on the target yet
+func isRefused(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// injectScript adds the live reload script to HTML responses
+func injectScript(resp *http.Response) error {
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") || resp.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	if i := bytes.LastIndex(bytes.ToLower(body), []byte("</body>")); i >= 0 {
+		body = append(body[:i], append([]byte(script), body[i:]...)...)
+	} else {
+		body = append(body, script...)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
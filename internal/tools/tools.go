@@ -3,9 +3,10 @@ package tools
 import (
 	"crypto/md5"
 	"fmt"
+	"hash/crc64"
+	"io"
 	"os"
 	"os/exec"
-	"path"
 	"strings"
 	"sync"
 	"time"
@@ -72,7 +73,9 @@ func GetFileModTime(path string) int64 {
 	return modTime
 }
 
-func GetPathDir(path string, contain []string) []string {
+// GetPathDirFunc returns path and all of its sub directories, skipping any
+// directory (and everything below it) for which skip returns true
+func GetPathDirFunc(path string, skip func(dir string) bool) []string {
 	var dirs []string
 	files, err := os.ReadDir(path)
 	if err != nil {
@@ -81,13 +84,12 @@ func GetPathDir(path string, contain []string) []string {
 
 	for _, file := range files {
 		if file.IsDir() {
-			name := file.Name()
-			if InArray(name, contain) {
+			npath := path + "/" + file.Name()
+			if skip(npath) {
 				continue
 			}
 
-			npath := path + "/" + name
-			ndirs := GetPathDir(npath, contain)
+			ndirs := GetPathDirFunc(npath, skip)
 			dirs = append(dirs, npath)
 
 			for _, f := range ndirs {
@@ -99,23 +101,6 @@ func GetPathDir(path string, contain []string) []string {
 	return dirs
 }
 
-func GetVailDir(paths []string, contain []string) []string {
-	var newDirs []string
-	for _, p := range paths {
-		files, _ := os.ReadDir(p)
-		for _, f := range files {
-			fname := f.Name()
-			suffix := path.Ext(fname)
-			suffix = strings.Trim(suffix, ".")
-			if InArray(suffix, contain) && !InArray(p, newDirs) {
-				newDirs = append(newDirs, p)
-				break
-			}
-		}
-	}
-	return newDirs
-}
-
 // IsFile returns true if given path exists as a file (i.e. not a directory).
 func IsFile(path string) bool {
 	f, e := os.Stat(path)
@@ -160,6 +145,23 @@ func Md5(s string) string {
 	return Md5Byte([]byte(s))
 }
 
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+// FileHash returns a fast, non-cryptographic checksum of the file content
+func FileHash(file string) (uint64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	hash := crc64.New(crcTable)
+	if _, err := io.Copy(hash, f); err != nil {
+		return 0, err
+	}
+	return hash.Sum64(), nil
+}
+
 // RunCommand executes a shell command and returns the output
 func RunCommand(command string, args ...string) (string, error) {
 	cmd := exec.Command(command, args...)
@@ -0,0 +1,234 @@
+package workspace
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Module is a local dependency living outside the project directory
+type Module struct {
+	Dir    string
+	Source string
+}
+
+// LocalModules returns the local modules the project at root depends on:
+// go.mod replace directives pointing to a directory, go.work use and replace
+// directives, and Cargo path dependencies. Modules are resolved transitively,
+// directories inside root or inside another module are left out.
+func LocalModules(root string) []Module {
+	root = filepath.Clean(root)
+
+	var modules []Module
+	seen := map[string]bool{root: true}
+	queue := []string{root}
+
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+
+		for _, m := range localDeps(dir) {
+			if seen[m.Dir] || !isDir(m.Dir) {
+				continue
+			}
+			seen[m.Dir] = true
+			queue = append(queue, m.Dir)
+
+			if !within(m.Dir, root) {
+				modules = append(modules, m)
+			}
+		}
+	}
+
+	// Nested modules are already covered by their parent
+	var result []Module
+	for _, m := range modules {
+		nested := false
+		for _, o := range modules {
+			if o.Dir != m.Dir && within(m.Dir, o.Dir) {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// localDeps parses the manifests found in dir
+func localDeps(dir string) []Module {
+	var deps []Module
+	for _, p := range goModPaths(filepath.Join(dir, "go.mod"), false) {
+		deps = append(deps, Module{Dir: resolve(dir, p), Source: "go.mod replace"})
+	}
+	for _, p := range goModPaths(filepath.Join(dir, "go.work"), true) {
+		deps = append(deps, Module{Dir: resolve(dir, p), Source: "go.work"})
+	}
+	for _, p := range cargoPaths(filepath.Join(dir, "Cargo.toml")) {
+		deps = append(deps, Module{Dir: resolve(dir, p), Source: "Cargo.toml path"})
+	}
+	return deps
+}
+
+// goModPaths returns the local directories of replace directives and, for
+// go.work files, of use directives
+func goModPaths(file string, work bool) []string {
+	var paths []string
+	eachDirective(file, func(verb string, args []string) {
+		switch verb {
+		case "use":
+			if work && len(args) > 0 {
+				paths = append(paths, args[0])
+			}
+		case "replace":
+			// old [version] => new [version], only a path without version is local
+			for i, a := range args {
+				if a == "=>" && len(args) == i+2 && isLocalPath(args[i+1]) {
+					paths = append(paths, args[i+1])
+				}
+			}
+		}
+	})
+	return paths
+}
+
+// eachDirective calls fn for every directive of a go.mod or go.work file,
+// expanding "verb ( ... )" blocks into one call per line
+func eachDirective(file string, fn func(verb string, args []string)) {
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	block := ""
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := splitFields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			fn(block, unquote(fields))
+			continue
+		}
+
+		if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+		fn(fields[0], unquote(fields[1:]))
+	}
+}
+
+var (
+	cargoSection = regexp.MustCompile(`^\s*\[+\s*([^\]]+?)\s*\]+`)
+	cargoPath    = regexp.MustCompile(`\bpath\s*=\s*"([^"]+)"`)
+)
+
+// cargoPaths returns the path dependencies of a Cargo.toml file
+func cargoPaths(file string) []string {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var paths []string
+	section := ""
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if m := cargoSection.FindStringSubmatch(line); m != nil {
+			section = m[1]
+			continue
+		}
+
+		// [dependencies], [dev-dependencies.x], [target.'cfg(..)'.dependencies], [patch.crates-io]
+		if !strings.Contains(section, "dependencies") && !strings.HasPrefix(section, "patch") {
+			continue
+		}
+		if m := cargoPath.FindStringSubmatch(line); m != nil {
+			paths = append(paths, m[1])
+		}
+	}
+	return paths
+}
+
+// splitFields splits a go.mod line on white space, keeping "quoted" and
+// `raw` strings in one piece
+func splitFields(line string) []string {
+	var fields []string
+	for {
+		line = strings.TrimLeft(line, " \t\r")
+		if line == "" {
+			return fields
+		}
+
+		end := strings.IndexAny(line, " \t\r")
+		if q := line[0]; q == '"' || q == '`' {
+			for i := 1; i < len(line); i++ {
+				if line[i] == '\\' && q == '"' {
+					i++
+				} else if line[i] == q {
+					end = i + 1
+					break
+				}
+			}
+		}
+		if end < 0 || end > len(line) {
+			end = len(line)
+		}
+		fields = append(fields, line[:end])
+		line = line[end:]
+	}
+}
+
+func unquote(fields []string) []string {
+	for i, f := range fields {
+		if u, err := strconv.Unquote(f); err == nil {
+			fields[i] = u
+		}
+	}
+	return fields
+}
+
+func isLocalPath(p string) bool {
+	return strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../") || filepath.IsAbs(p) ||
+		strings.HasPrefix(p, `.\`) || strings.HasPrefix(p, `..\`)
+}
+
+func resolve(dir, p string) string {
+	if filepath.IsAbs(p) {
+		return filepath.Clean(p)
+	}
+	return filepath.Join(dir, filepath.FromSlash(p))
+}
+
+// within reports whether dir equals parent or lies below it
+func within(dir, parent string) bool {
+	rel, err := filepath.Rel(parent, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func isDir(dir string) bool {
+	fi, err := os.Stat(dir)
+	return err == nil && fi.IsDir()
+}
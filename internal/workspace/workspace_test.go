@@ -0,0 +1,129 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFile(t *testing.T, file, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGoModPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		work    bool
+		want    []string
+	}{
+		{
+			name:    "single replace",
+			content: "module a\n\nreplace example.com/b => ../b\n",
+			want:    []string{"../b"},
+		},
+		{
+			name: "replace block",
+			content: "module a\n\nreplace (\n" +
+				"\texample.com/b => ../b // local copy\n" +
+				"\texample.com/c v1.0.0 => ./c\n" +
+				"\texample.com/d => example.com/e v1.2.0\n" +
+				")\n",
+			want: []string{"../b", "./c"},
+		},
+		{
+			name:    "quoted path",
+			content: "module a\nreplace example.com/b => \"../my b\"\n",
+			want:    []string{"../my b"},
+		},
+		{
+			name:    "raw quoted path",
+			content: "module a\nreplace example.com/b => `../raw b`\n",
+			want:    []string{"../raw b"},
+		},
+		{
+			name:    "use ignored in go.mod",
+			content: "module a\nuse ./b\n",
+		},
+		{
+			name:    "go.work use and replace",
+			content: "go 1.18\n\nuse (\n\t.\n\t./tools\n\t../shared\n)\nreplace example.com/x => ../x\n",
+			work:    true,
+			want:    []string{".", "./tools", "../shared", "../x"},
+		},
+		{
+			name:    "commented out",
+			content: "module a\n// replace example.com/b => ../b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		file := filepath.Join(t.TempDir(), "go.mod")
+		writeFile(t, file, tt.content)
+
+		got := goModPaths(file, tt.work)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: goModPaths() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCargoPaths(t *testing.T) {
+	content := `[package]
+name = "app"
+path = "not/a/dependency"
+
+[dependencies]
+serde = "1"
+core = { path = "../core" }
+util = { version = "0.1", path = "../util" } # local
+# old = { path = "../old" }
+
+[dev-dependencies.testkit]
+path = "../testkit"
+
+[target.'cfg(unix)'.dependencies]
+nix = { path = "../nix" }
+
+[patch.crates-io]
+log = { path = "../log" }
+
+[[bin]]
+name = "app"
+path = "src/main.rs"
+`
+	file := filepath.Join(t.TempDir(), "Cargo.toml")
+	writeFile(t, file, content)
+
+	want := []string{"../core", "../util", "../testkit", "../nix", "../log"}
+	if got := cargoPaths(file); !reflect.DeepEqual(got, want) {
+		t.Errorf("cargoPaths() = %q, want %q", got, want)
+	}
+}
+
+func TestLocalModules(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "app")
+
+	writeFile(t, filepath.Join(root, "go.mod"), "module app\n\nreplace (\n\tlib => ../lib\n\tinner => ./inner\n\tgone => ../gone\n)\n")
+	writeFile(t, filepath.Join(root, "inner", "go.mod"), "module inner\n")
+	// Transitive dependency, and a module nested in another one
+	writeFile(t, filepath.Join(base, "lib", "go.mod"), "module lib\n\nreplace (\n\tshared => ../shared\n\tsub => ./sub\n)\n")
+	writeFile(t, filepath.Join(base, "lib", "sub", "go.mod"), "module sub\n")
+	writeFile(t, filepath.Join(base, "shared", "Cargo.toml"), "[dependencies]\napp = { path = \"../app\" }\n")
+
+	want := []Module{
+		{Dir: filepath.Join(base, "lib"), Source: "go.mod replace"},
+		{Dir: filepath.Join(base, "shared"), Source: "go.mod replace"},
+	}
+	if got := LocalModules(root); !reflect.DeepEqual(got, want) {
+		t.Errorf("LocalModules() = %+v, want %+v", got, want)
+	}
+}
@@ -32,6 +32,7 @@ func main() {
 		cmd.Version,
 		cmd.Status,
 		cmd.Optimize,
+		cmd.Clean,
 	}
 
 	if err := app.Run(os.Args); err != nil {